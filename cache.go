@@ -0,0 +1,472 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// archiveMeta describes a cached archive; it is stored next to the archive as <sha256>.json
+type archiveMeta struct {
+	Filename string    `json:"filename"`
+	Version  string    `json:"version"`
+	OS       string    `json:"os"`
+	Arch     string    `json:"arch"`
+	Size     int64     `json:"size"`
+	Fetched  time.Time `json:"fetched"`
+}
+
+// cachedArchive is an entry of the archive cache
+type cachedArchive struct {
+	SHA256   string
+	Path     string
+	Size     int64
+	LastUsed time.Time
+	Partial  bool
+	InUse    bool // A partial download that a running process is still writing
+	Meta     *archiveMeta
+}
+
+// cacheDir returns the root of the getgo cache ($XDG_CACHE_HOME/getgo by default)
+func cacheDir() (string, error) {
+	if dir := os.Getenv("GETGO_CACHE"); dir != "" {
+		return expandPath(dir)
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating cache directory: %v", err)
+	}
+	return filepath.Join(dir, "getgo"), nil
+}
+
+// archiveCacheDir returns the content-addressed archive cache, creating it if needed
+func archiveCacheDir() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "archives")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating archive cache: %v", err)
+	}
+	return dir, nil
+}
+
+// lookupCachedArchive returns the cached copy of file if it is present and intact
+func lookupCachedArchive(dir string, file *GoFile) (string, bool) {
+	path := filepath.Join(dir, strings.ToLower(file.SHA256))
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+
+	// Never trust the cache blindly, a corrupted entry is dropped and downloaded again
+	if err := verifyArchive(path, file); err != nil {
+		color.Yellow("Discarding corrupted cache entry %s: %v", path, err)
+		os.Remove(path)
+		return "", false
+	}
+
+	// Record the use so that pruning by age keeps archives that are still needed
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return path, true
+}
+
+// isArchiveCached reports whether the archive cache holds a copy of file
+func isArchiveCached(file *GoFile) bool {
+	if file == nil {
+		return false
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(dir, "archives", strings.ToLower(file.SHA256)))
+	return err == nil
+}
+
+// fetchArchive returns a local, verified copy of the archive name from the download mirrors.
+// Archives with a known checksum are served from and stored in the archive cache, keyed by
+// their SHA-256, and a partial download there is resumed by the next run. Other archives are
+// downloaded into tempDir; the returned cached flag is false for those, which the caller must remove.
+func fetchArchive(ctx context.Context, name string, file *GoFile, tempDir string) (path string, cached bool, err error) {
+	dir := ""
+	if file != nil {
+		if dir, err = archiveCacheDir(); err != nil {
+			color.Yellow("Archive cache unavailable: %v", err)
+			dir = ""
+		}
+	}
+
+	if dir != "" {
+		if path, ok := lookupCachedArchive(dir, file); ok {
+			color.Green("Using cached archive %s", path)
+			return path, true, nil
+		}
+		path = filepath.Join(dir, strings.ToLower(file.SHA256))
+	} else {
+		path = filepath.Join(tempDir, name)
+	}
+
+	if offline {
+		if file == nil {
+			return "", false, fmt.Errorf("offline: %s cannot be downloaded and is not cached", name)
+		}
+		return "", false, fmt.Errorf("offline: archive %s (sha256 %s) is not in the archive cache at %s", name, file.SHA256, dir)
+	}
+
+	// Each process downloads into a file of its own, which becomes the cache entry only once
+	// verified, so concurrent installs of the same archive never write to the same file
+	target := path
+	if dir != "" {
+		target = fmt.Sprintf("%s.%d", path, os.Getpid())
+		claimPartialDownload(dir, strings.ToLower(file.SHA256), target+".part")
+	}
+
+	color.Cyan("Downloading %s...", name)
+	if err := downloadFromMirrors(ctx, name, target); err != nil {
+		return "", false, err
+	}
+
+	if file == nil {
+		color.Yellow("Skipping checksum verification")
+		return target, false, nil
+	}
+
+	// Verify the archive before anyone can use it
+	color.Cyan("Verifying SHA-256 checksum...")
+	if err := verifyArchive(target, file); err != nil {
+		os.Remove(target)
+		return "", false, fmt.Errorf("error verifying Go archive: %v", err)
+	}
+	color.Green("Checksum verified: %s", file.SHA256)
+
+	if dir == "" {
+		return target, false, nil
+	}
+	if err := os.Rename(target, path); err != nil {
+		os.Remove(target)
+		return "", false, fmt.Errorf("error adding %s to the archive cache: %v", name, err)
+	}
+
+	meta := archiveMeta{
+		Filename: file.Filename,
+		Version:  file.Version,
+		OS:       file.OS,
+		Arch:     file.Arch,
+		Size:     file.Size,
+		Fetched:  time.Now().UTC(),
+	}
+	if content, err := json.MarshalIndent(meta, "", "  "); err == nil {
+		writeFileAtomic(path+".json", content)
+	}
+	return path, true, nil
+}
+
+// parseDownloadName splits the name of a download in progress or interrupted, <sha>.<pid>.part,
+// or <sha>.<pid> once downloaded but not yet verified, into the SHA-256 and the process ID
+func parseDownloadName(name string) (string, int, bool) {
+	sha, rest, ok := strings.Cut(strings.TrimSuffix(name, ".part"), ".")
+	if !ok {
+		return "", 0, false
+	}
+	pid, err := strconv.Atoi(rest)
+	if err != nil {
+		return "", 0, false
+	}
+	return sha, pid, true
+}
+
+// claimPartialDownload renames a partial download of the archive with the given SHA-256 that a
+// process no longer running left in the cache to partPath, so it is resumed instead of started
+// over. The rename is atomic, so of several processes only one gets it.
+func claimPartialDownload(dir, sha, partPath string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		entrySHA, pid, ok := parseDownloadName(name)
+		if !ok || entrySHA != sha || !strings.HasSuffix(name, ".part") || processAlive(pid) {
+			continue
+		}
+		if os.Rename(filepath.Join(dir, name), partPath) == nil {
+			return
+		}
+	}
+}
+
+// listCachedArchives returns the entries of the archive cache, most recently used first
+func listCachedArchives(dir string) ([]cachedArchive, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []cachedArchive
+	for _, entry := range entries {
+		name := entry.Name()
+		// Skip metadata, and the temporary files writeFileAtomic renames into place
+		if entry.IsDir() || strings.HasSuffix(name, ".json") || strings.Contains(name, ".tmp-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		archive := cachedArchive{
+			SHA256:   name,
+			Path:     filepath.Join(dir, name),
+			Size:     info.Size(),
+			LastUsed: info.ModTime(),
+		}
+		if strings.Contains(name, ".") {
+			sha, pid, ok := parseDownloadName(name)
+			if !ok {
+				continue
+			}
+			archive.SHA256, archive.Partial, archive.InUse = sha, true, processAlive(pid)
+		}
+		if content, err := os.ReadFile(filepath.Join(dir, archive.SHA256+".json")); err == nil {
+			var meta archiveMeta
+			if json.Unmarshal(content, &meta) == nil {
+				archive.Meta = &meta
+			}
+		}
+		archives = append(archives, archive)
+	}
+
+	slices.SortFunc(archives, func(a, b cachedArchive) int {
+		return b.LastUsed.Compare(a.LastUsed)
+	})
+	return archives, nil
+}
+
+// removeCachedArchive deletes a cache entry together with its metadata
+func removeCachedArchive(archive cachedArchive) error {
+	if err := os.Remove(archive.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !archive.Partial {
+		os.Remove(filepath.Join(filepath.Dir(archive.Path), archive.SHA256+".json"))
+	}
+	return nil
+}
+
+// parseAge parses a duration that may also be given in days, such as "30d"
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// parseSize parses a size such as "500M", "2GB" or "1GiB" into bytes
+func parseSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	upper = strings.TrimSuffix(strings.TrimSuffix(upper, "B"), "I")
+
+	multiplier := int64(1)
+	if upper != "" {
+		if i := strings.IndexByte("KMGT", upper[len(upper)-1]); i >= 0 {
+			multiplier = int64(1) << (10 * (i + 1))
+			upper = upper[:len(upper)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(upper), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// printCacheUsage prints the usage information for the cache command
+func printCacheUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Printf("%s: getgo cache <list|prune|clear> [options]\n", bold("Usage"))
+	fmt.Printf("%s:\n", bold("Examples"))
+	fmt.Printf("  %s                     # Show cached archives and their size\n", cyan("getgo cache list"))
+	fmt.Printf("  %s      # Remove archives not used for 30 days\n", cyan("getgo cache prune --max-age 30d"))
+	fmt.Printf("  %s     # Keep at most 2 GiB, dropping least recently used\n", cyan("getgo cache prune --max-size 2G"))
+	fmt.Printf("  %s                    # Remove all cached archives\n", cyan("getgo cache clear"))
+
+	fmt.Printf("\n%s:\n", bold("Options"))
+	fmt.Printf("  --max-age AGE      Remove archives not used for AGE (e.g. 30d, 72h)\n")
+	fmt.Printf("  --max-size SIZE    Remove least recently used archives until the cache fits SIZE (e.g. 2G)\n")
+
+	fmt.Printf("\nThe cache lives in $XDG_CACHE_HOME/getgo (override with GETGO_CACHE).\n")
+}
+
+// runCache manages the archive cache
+func runCache(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("cache", flag.ExitOnError)
+	fs.Usage = printCacheUsage
+	maxAgeFlag := fs.String("max-age", "", "Remove archives not used for this long")
+	maxSizeFlag := fs.String("max-size", "", "Remove least recently used archives beyond this size")
+	args = parseArgs(fs, args)
+
+	if len(args) != 1 {
+		printCacheUsage()
+		os.Exit(1)
+	}
+
+	dir, err := archiveCacheDir()
+	if err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+
+	archives, err := listCachedArchives(dir)
+	if err != nil {
+		color.Red("Error reading archive cache: %v", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		if len(archives) == 0 {
+			color.Yellow("The archive cache in %s is empty", dir)
+			return
+		}
+
+		bold := color.New(color.Bold).SprintFunc()
+		fmt.Printf("%s\n", bold(fmt.Sprintf("%-14s %-36s %-10s %s", "SHA256", "FILE", "SIZE", "LAST USED")))
+
+		var total int64
+		for _, archive := range archives {
+			name := "?"
+			if archive.Meta != nil {
+				name = archive.Meta.Filename
+			}
+			switch {
+			case archive.InUse:
+				name = "(downloading)"
+			case archive.Partial:
+				name = "(partial download)"
+			}
+			fmt.Printf("%-14s %-36s %-10s %s\n", archive.SHA256[:min(12, len(archive.SHA256))], name,
+				formatSize(archive.Size), archive.LastUsed.Format("2006-01-02 15:04"))
+			total += archive.Size
+		}
+		fmt.Printf("\n%d archive(s), %s in %s\n", len(archives), formatSize(total), dir)
+
+	case "prune":
+		if *maxAgeFlag == "" && *maxSizeFlag == "" {
+			color.Red("Specify --max-age and/or --max-size")
+			os.Exit(1)
+		}
+
+		// Downloads that running processes are still writing are left alone
+		archives = slices.DeleteFunc(archives, func(archive cachedArchive) bool { return archive.InUse })
+
+		var freed int64
+		removed := 0
+
+		// remove drops an archive from the cache and accounts for it
+		remove := func(archive cachedArchive) {
+			if err := removeCachedArchive(archive); err != nil {
+				color.Red("Error removing %s: %v", archive.Path, err)
+				return
+			}
+			freed += archive.Size
+			removed++
+		}
+
+		keep := archives
+		if *maxAgeFlag != "" {
+			maxAge, err := parseAge(*maxAgeFlag)
+			if err != nil {
+				color.Red("Error: %v", err)
+				os.Exit(1)
+			}
+			keep = nil
+			for _, archive := range archives {
+				if time.Since(archive.LastUsed) > maxAge {
+					remove(archive)
+				} else {
+					keep = append(keep, archive)
+				}
+			}
+		}
+
+		if *maxSizeFlag != "" {
+			maxSize, err := parseSize(*maxSizeFlag)
+			if err != nil {
+				color.Red("Error: %v", err)
+				os.Exit(1)
+			}
+			var size int64
+			for _, archive := range keep {
+				size += archive.Size
+			}
+			// Archives are sorted most recently used first, so drop from the end
+			for i := len(keep) - 1; i >= 0 && size > maxSize; i-- {
+				remove(keep[i])
+				size -= keep[i].Size
+			}
+		}
+
+		color.Green("Removed %d archive(s), freed %s", removed, formatSize(freed))
+
+	case "clear":
+		var freed int64
+		removed, inUse := 0, 0
+		for _, archive := range archives {
+			if archive.InUse {
+				inUse++
+				continue
+			}
+			freed += archive.Size
+			removed++
+		}
+
+		if inUse == 0 {
+			if err := os.RemoveAll(dir); err != nil {
+				color.Red("Error clearing archive cache: %v", err)
+				os.Exit(1)
+			}
+		} else {
+			// Keep the downloads running processes are still writing
+			for _, archive := range archives {
+				if archive.InUse {
+					continue
+				}
+				if err := removeCachedArchive(archive); err != nil {
+					color.Red("Error removing %s: %v", archive.Path, err)
+					os.Exit(1)
+				}
+			}
+			color.Yellow("Kept %d download(s) still in progress", inUse)
+		}
+		color.Green("Removed %d archive(s), freed %s", removed, formatSize(freed))
+
+	default:
+		printCacheUsage()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds the settings read from the getgo configuration file and environment
+type Config struct {
+	Root           string   `json:"root"`            // Install root used by shims
+	ShimOrder      []string `json:"shim_order"`      // Project files consulted by shims, in order
+	DefaultVersion string   `json:"default_version"` // Version used when no project file is found
+	AutoInstall    bool     `json:"auto_install"`    // Install missing versions when a shim needs them
+	ManifestTTL    string   `json:"manifest_ttl"`    // How long the cached release manifest is used, e.g. "1h"
+	Mirrors        []string `json:"mirrors"`         // Download base URLs, tried in order
+	Source         string   `json:"source"`          // Download source: "dl" or "proxy"
+}
+
+// defaultConfig returns the built-in configuration
+func defaultConfig() *Config {
+	return &Config{
+		Root:      "~/.go",
+		ShimOrder: []string{".go-version", "go.work", "go.mod"},
+	}
+}
+
+// configPath returns the location of the configuration file
+func configPath() (string, error) {
+	if path := os.Getenv("GETGO_CONFIG"); path != "" {
+		return expandPath(path)
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating configuration directory: %v", err)
+	}
+	return filepath.Join(dir, "getgo", "config.json"), nil
+}
+
+// readConfigFile returns the built-in configuration overlaid with the configuration file, if any.
+// Unlike loadConfig it applies no environment overrides, so the result can be saved back.
+func readConfigFile() (*Config, error) {
+	cfg := defaultConfig()
+
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, cfg); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// loadConfig reads the configuration file, if any, and applies GETGO_* environment overrides
+func loadConfig() (*Config, error) {
+	cfg, err := readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// Environment variables take precedence over the file
+	if root := os.Getenv("GETGO_ROOT"); root != "" {
+		cfg.Root = root
+	}
+	if order := os.Getenv("GETGO_SHIM_ORDER"); order != "" {
+		cfg.ShimOrder = splitList(order)
+	}
+	if version := os.Getenv("GETGO_DEFAULT_VERSION"); version != "" {
+		cfg.DefaultVersion = version
+	}
+	if auto := os.Getenv("GETGO_AUTO_INSTALL"); auto != "" {
+		cfg.AutoInstall = auto == "1" || strings.EqualFold(auto, "true")
+	}
+
+	cfg.Root, err = expandPath(cfg.Root)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// saveConfig writes cfg to the configuration file
+func saveConfig(cfg *Config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating configuration directory: %v", err)
+	}
+
+	content, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+
+	"github.com/fatih/color"
+)
+
+// currentLinkName is the symlink in an install root that points at the default toolchain
+const currentLinkName = "go"
+
+// currentLinkPath returns the path of the current symlink in installPath
+func currentLinkPath(installPath string) string {
+	return filepath.Join(installPath, currentLinkName)
+}
+
+// currentGoroot returns the toolchain directory the current symlink of installPath points to, or ""
+func currentGoroot(installPath string) string {
+	link := currentLinkPath(installPath)
+	target, err := os.Readlink(link)
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(installPath, target)
+	}
+	return target
+}
+
+// defaultGopath returns the GOPATH the go command uses when none is passed with --path
+func defaultGopath() (string, error) {
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		return gopath, nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("error getting current user: %v", err)
+	}
+	return filepath.Join(usr.HomeDir, "go"), nil
+}
+
+// checkCurrentLink returns an error if the current symlink of installPath would be a GOPATH
+// entry or lie inside one. Installing into the home directory would otherwise turn ~/go into
+// a link to the toolchain, making GOPATH and GOROOT the same directory.
+func checkCurrentLink(installPath, gopath string) error {
+	link, err := filepath.Abs(currentLinkPath(installPath))
+	if err != nil {
+		return err
+	}
+	for _, dir := range filepath.SplitList(gopath) {
+		if dir == "" {
+			continue
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, link); err == nil && (rel == "." || filepath.IsLocal(rel)) {
+			return fmt.Errorf("%s would collide with GOPATH %s; use an install path outside GOPATH, such as ~/.go", link, dir)
+		}
+	}
+	return nil
+}
+
+// setCurrentGo atomically points the current symlink of installPath at goroot.
+// The new link is created under a temporary name and renamed over the old one,
+// so there is no moment at which the link is missing. The link is never created
+// inside gopath.
+func setCurrentGo(installPath, goroot, gopath string) error {
+	if err := checkCurrentLink(installPath, gopath); err != nil {
+		return err
+	}
+	link := currentLinkPath(installPath)
+	if info, err := os.Lstat(link); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("%s exists and is not a symlink", link)
+	}
+
+	// Use a relative target so the install root can be moved as a whole
+	target, err := filepath.Rel(installPath, goroot)
+	if err != nil {
+		target = goroot
+	}
+
+	tmpLink := filepath.Join(installPath, fmt.Sprintf(".%s.tmp-%d", currentLinkName, os.Getpid()))
+	os.Remove(tmpLink)
+	if err := os.Symlink(target, tmpLink); err != nil {
+		return fmt.Errorf("error creating symlink: %v", err)
+	}
+
+	// Windows cannot rename over an existing directory link
+	if runtime.GOOS == "windows" {
+		os.Remove(link)
+	}
+
+	if err := os.Rename(tmpLink, link); err != nil {
+		os.Remove(tmpLink)
+		return fmt.Errorf("error replacing %s: %v", link, err)
+	}
+	return nil
+}
+
+// printUseUsage prints the usage information for the use command
+func printUseUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Printf("%s: getgo use <version> [install_path]\n", bold("Usage"))
+	fmt.Printf("%s:\n", bold("Examples"))
+	fmt.Printf("  %s          # Point ./go at ./go1.23.4\n", cyan("getgo use 1.23.4"))
+	fmt.Printf("  %s      # Point ~/.go/go at the newest installed 1.22.x\n", cyan("getgo use 1.22 ~/.go"))
+	fmt.Printf("\nSet GOROOT to install_path/go once; switching versions then only moves the symlink.\n")
+}
+
+// runUse switches the default toolchain of an install root
+func runUse(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("use", flag.ExitOnError)
+	fs.Usage = printUseUsage
+	args = parseArgs(fs, args)
+
+	installPath := "."
+	switch len(args) {
+	case 1:
+	case 2:
+		installPath = args[1]
+	default:
+		printUseUsage()
+		os.Exit(1)
+	}
+	installPath = expandPathOrExit(installPath)
+
+	goInst, err := resolveInstalled(installPath, args[0])
+	if err != nil {
+		color.Red("Error: %v", err)
+		fmt.Printf("Install it first with 'getgo --default %s %s'\n", args[0], installPath)
+		os.Exit(1)
+	}
+
+	gopath, err := defaultGopath()
+	if err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+	if err := setCurrentGo(installPath, goInst.Dir, gopath); err != nil {
+		color.Red("Error switching to Go %s: %v", goInst.Version, err)
+		os.Exit(1)
+	}
+
+	link := currentLinkPath(installPath)
+	color.Green("Now using Go %s (%s -> %s)", goInst.Version, link, filepath.Base(goInst.Dir))
+
+	if os.Getenv("GOROOT") != link {
+		color.Yellow("Set GOROOT=%s to follow the default version", link)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// maxDownloadAttempts is how often a download is tried before giving up on a mirror
+	maxDownloadAttempts = 5
+
+	// initialBackoff and maxBackoff bound the delay between download attempts
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+// progressReader is a custom io.Reader that tracks download progress
+type progressReader struct {
+	reader         io.Reader
+	totalBytes     int64
+	readBytes      int64
+	lastPercentage int
+	lastUpdateTime time.Time
+}
+
+func newProgressReader(reader io.Reader, totalBytes int64) *progressReader {
+	return &progressReader{
+		reader:         reader,
+		totalBytes:     totalBytes,
+		lastUpdateTime: time.Now(),
+	}
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.reader.Read(p)
+	pr.readBytes += int64(n)
+
+	// Update progress every 100ms to avoid too many updates
+	if time.Since(pr.lastUpdateTime) > 100*time.Millisecond {
+		percentage := int(float64(pr.readBytes) / float64(pr.totalBytes) * 100)
+
+		// Only update if percentage changed
+		if percentage != pr.lastPercentage && percentage <= 100 {
+			pr.lastPercentage = percentage
+			pr.lastUpdateTime = time.Now()
+
+			// Create progress bar (Windows-compatible approach)
+			progressBar := renderProgressBar(percentage)
+
+			// Print the progress bar
+			fmt.Print(progressBar)
+		}
+	}
+
+	return n, err
+}
+
+// renderProgressBar creates a progress bar string that works on all platforms
+func renderProgressBar(percentage int) string {
+	// Ensure percentage is within bounds
+	if percentage < 0 {
+		percentage = 0
+	} else if percentage > 100 {
+		percentage = 100
+	}
+
+	// Calculate the width of the progress bar (50 characters)
+	width := 50
+	completed := width * percentage / 100
+
+	// Build the progress bar
+	var sb strings.Builder
+
+	// Use carriage return to return to beginning of line
+	sb.WriteString("\r")
+
+	// Write the progress bar
+	sb.WriteString("Downloading: [")
+	sb.WriteString(strings.Repeat("=", completed))
+	if completed < width {
+		sb.WriteString(strings.Repeat(" ", width-completed))
+	}
+	sb.WriteString("] ")
+
+	// Write the percentage
+	sb.WriteString(fmt.Sprintf("%3d%%", percentage))
+
+	return sb.String()
+}
+
+// httpStatusError is returned when a server answers with an unexpected status code
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+	URL        string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("bad status: %s (URL: %s)", e.Status, e.URL)
+}
+
+// isTransient reports whether a download error is worth retrying
+func isTransient(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusRequestedRangeNotSatisfiable:
+			return true
+		}
+		return statusErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// backoff returns the delay before the given retry. The delay doubles with each retry up to
+// maxBackoff, with equal jitter: half of it is fixed and the other half random, so retries
+// from many clients spread out but never follow each other immediately.
+func backoff(attempt int) time.Duration {
+	delay := initialBackoff << (attempt - 1)
+	if delay <= 0 || delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay/2 + rand.N(delay/2)
+}
+
+// httpGet sends a GET request for url that is aborted when ctx is canceled
+func httpGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// sleepContext waits for d, returning early with the context's error if ctx is canceled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// downloadFileWithProgress downloads url to dest. Data is written to dest+".part" first,
+// which is resumed with a Range request on the next attempt if the server supports it,
+// and transient errors are retried with exponential backoff up to the given number of attempts.
+// A canceled download keeps its .part file; callers remove it unless it is worth resuming.
+func downloadFileWithProgress(ctx context.Context, url, dest string, attempts int) error {
+	partPath := dest + ".part"
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := backoff(attempt)
+			fmt.Println() // End the progress bar line
+			color.Yellow("Download failed: %v", err)
+			color.Yellow("Retrying in %s (attempt %d of %d)...", delay.Round(100*time.Millisecond), attempt+1, attempts)
+			if err = sleepContext(ctx, delay); err != nil {
+				break
+			}
+		}
+
+		err = downloadPart(ctx, url, partPath)
+		if err == nil || ctx.Err() != nil || !isTransient(err) {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if err != nil {
+		if info, statErr := os.Stat(partPath); statErr == nil && info.Size() > 0 {
+			return fmt.Errorf("%w (partial download kept at %s, run again to resume)", err, partPath)
+		}
+		return err
+	}
+
+	return os.Rename(partPath, dest)
+}
+
+// downloadPart downloads url into partPath, continuing an existing partial file when possible
+func downloadPart(ctx context.Context, url, partPath string) error {
+	// Send HEAD request to get the file size and range support
+	headReq, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+	headResp, err := http.DefaultClient.Do(headReq)
+	if err != nil {
+		return err
+	}
+	headResp.Body.Close()
+
+	if headResp.StatusCode != http.StatusOK {
+		return &httpStatusError{StatusCode: headResp.StatusCode, Status: headResp.Status, URL: url}
+	}
+
+	totalBytes := headResp.ContentLength
+	acceptRanges := strings.Contains(headResp.Header.Get("Accept-Ranges"), "bytes")
+
+	// Work out how much of the file we already have
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+	if !acceptRanges || (totalBytes > 0 && offset > totalBytes) {
+		offset = 0
+	}
+	if totalBytes > 0 && offset == totalBytes {
+		fmt.Print(renderProgressBar(100))
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 &&
+		strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)):
+		color.Cyan("Resuming download at %s of %s", formatSize(offset), formatSize(totalBytes))
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		// The server sent the whole file, start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file does not fit the remote file, start over on the next attempt
+		os.Remove(partPath)
+		return &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, URL: url}
+	default:
+		return &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, URL: url}
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Create a progress reader that accounts for the bytes we already have
+	progressR := newProgressReader(resp.Body, totalBytes)
+	progressR.readBytes = offset
+
+	// Copy the data using the progress reader, keeping whatever arrived on failure
+	_, err = io.Copy(out, progressR)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Ensure the progress bar shows 100% when download is complete
+	fmt.Print(renderProgressBar(100))
+
+	return nil
+}
+
+// streamArchive downloads a .tar.gz archive from url and extracts it into dst on the fly,
+// hashing the compressed bytes as they pass. Unless it returns nil, the caller must discard
+// whatever was extracted; a nil file skips verification.
+func streamArchive(ctx context.Context, url string, file *GoFile, dst string) error {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, URL: url}
+	}
+
+	h := sha256.New()
+	progressR := newProgressReader(resp.Body, resp.ContentLength)
+	body := io.TeeReader(progressR, h)
+	if err := extractTarGz(ctx, body, dst); err != nil {
+		return err
+	}
+
+	// Hash whatever follows the end of the tar stream, such as padding
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return err
+	}
+	fmt.Println(renderProgressBar(100))
+
+	if file == nil {
+		return nil
+	}
+	return matchDigest(file, progressR.readBytes, h.Sum(nil))
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// envFormats lists the output formats of the env command
+var envFormats = []string{"shell", "dotenv", "json", "make"}
+
+// envChange is a change the env command makes to one environment variable
+type envChange struct {
+	name  string
+	value string
+	list  []string // Entries of a list variable like PATH, which value joins
+	unset bool
+}
+
+// printEnvUsage prints the usage information for the env command
+func printEnvUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Printf("%s: getgo env [options] [version] [install_path]\n", bold("Usage"))
+	fmt.Printf("       getgo env --unset [options] [install_path]\n")
+	fmt.Printf("       getgo env <--update|--remove> [options] [install_path]\n")
+	fmt.Printf("%s:\n", bold("Examples"))
+	fmt.Printf("  %s            # Activate the newest installed 1.22.x in this shell\n", cyan(`eval "$(getgo env 1.22)"`))
+	fmt.Printf("  %s          # Undo it\n", cyan(`eval "$(getgo env --unset)"`))
+	fmt.Printf("  %s             # Activate the default Go in fish\n", cyan("getgo env --shell fish | source"))
+	fmt.Printf("  %s  # Write a .env file for docker compose\n", cyan("getgo env --format dotenv 1.23 > .env"))
+	fmt.Printf("  %s                 # Point the shell configuration at the default Go\n", cyan("getgo env --update"))
+	fmt.Printf("  %s       # Show what would change in the shell configuration\n", cyan("getgo env --update --dry-run"))
+	fmt.Printf("  %s                 # Remove the getgo block from all shell configuration files\n", cyan("getgo env --remove"))
+
+	fmt.Printf("\n%s:\n", bold("Options"))
+	fmt.Printf("  --shell SHELL      Shell syntax to print: %s (default: the current shell)\n", joinShells())
+	fmt.Printf("  --format FORMAT    Output format: %s (default: shell)\n", strings.Join(envFormats, ", "))
+	fmt.Printf("  --unset            Print the changes that undo the activation\n")
+	fmt.Printf("  --update           Write the getgo block for the install_path/go symlink, replacing an older one\n")
+	fmt.Printf("  --remove           Remove the getgo block\n")
+	fmt.Printf("  --dry-run          Print the changes as a diff instead of writing them\n")
+	fmt.Printf("  --file FILE        Shell configuration file to edit (default: that of the current shell, or all for --remove)\n")
+	fmt.Printf("  -p, --path PATH    GOPATH to set (default: $GOPATH, or $HOME/go)\n")
+
+	fmt.Printf("\nWithout a version, the default Go (the install_path/go symlink) is activated. The install\n")
+	fmt.Printf("path defaults to the configured root. Activation puts GOROOT/bin and GOPATH/bin first in PATH\n")
+	fmt.Printf("and drops the bin directories of other toolchains, so it can be repeated to switch versions.\n")
+	fmt.Printf("\nThe block is enclosed in %q and %q lines. A backup of the previous\n", rcBlockBegin, rcBlockEnd)
+	fmt.Printf("content is written to <file>%s before each change.\n", rcBackupSuffix)
+}
+
+// joinShells returns the names of the shells getgo writes for, separated by commas
+func joinShells() string {
+	var names []string
+	for _, sh := range shells {
+		names = append(names, string(sh))
+	}
+	return strings.Join(names, ", ")
+}
+
+// runEnv prints the environment of an installed toolchain, or manages the getgo block in shell
+// configuration files
+func runEnv(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("env", flag.ExitOnError)
+	fs.Usage = printEnvUsage
+	shellFlag := fs.String("shell", "", "Shell syntax to print")
+	formatFlag := fs.String("format", "shell", "Output format")
+	unsetFlag := fs.Bool("unset", false, "Print the changes that undo the activation")
+	updateFlag := fs.Bool("update", false, "Write the getgo block")
+	removeFlag := fs.Bool("remove", false, "Remove the getgo block")
+	dryRunFlag := fs.Bool("dry-run", false, "Print the changes as a diff instead of writing them")
+	fileFlag := fs.String("file", "", "Shell configuration file to edit")
+	gopathFlag := fs.String("path", "", "GOPATH to set")
+	gopathShortFlag := fs.String("p", "", "GOPATH to set (shorthand)")
+	args = parseArgs(fs, args)
+
+	var sh shell
+	if *shellFlag != "" {
+		var ok bool
+		if sh, ok = parseShell(*shellFlag); !ok {
+			color.Red("Error: unknown shell %q (supported: %s)", *shellFlag, joinShells())
+			os.Exit(1)
+		}
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		color.Red("Error getting current user: %v", err)
+		os.Exit(1)
+	}
+
+	gopath := getCustomGOPATH(gopathFlag, gopathShortFlag)
+	if gopath == "" {
+		gopath = os.Getenv("GOPATH")
+	}
+	if gopath == "" {
+		gopath = filepath.Join(usr.HomeDir, "go")
+	}
+	gopath = expandPathOrExit(gopath)
+
+	if *updateFlag || *removeFlag {
+		if *updateFlag == *removeFlag || *unsetFlag || len(args) > 1 {
+			printEnvUsage()
+			os.Exit(1)
+		}
+		updateShellConfig(usr.HomeDir, *fileFlag, args, sh, gopath, *removeFlag, *dryRunFlag)
+		return
+	}
+
+	if !slices.Contains(envFormats, *formatFlag) || len(args) > 2 || (*unsetFlag && len(args) > 1) {
+		printEnvUsage()
+		os.Exit(1)
+	}
+	if sh == "" {
+		sh = detectShell()
+	}
+
+	// The output is meant for eval, so diagnostics go to stderr
+	color.Output = color.Error
+
+	cfg, err := loadConfig()
+	if err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+	// A single argument is the install path if it looks like one, as no version spec does
+	spec := ""
+	switch {
+	case len(args) == 1 && (*unsetFlag || looksLikePath(args[0])):
+		cfg.Root = expandPathOrExit(args[0])
+	case len(args) == 2:
+		spec, cfg.Root = args[0], expandPathOrExit(args[1])
+	case len(args) == 1:
+		spec = args[0]
+	}
+
+	var changes []envChange
+	if *unsetFlag {
+		changes = unsetChanges(cfg.Root)
+	} else {
+		goroot, err := envGoroot(cfg.Root, spec)
+		if err != nil {
+			color.Red("Error: %v", err)
+			os.Exit(1)
+		}
+		changes = activationChanges(cfg.Root, goroot, gopath)
+	}
+
+	out, err := renderEnv(changes, *formatFlag, sh)
+	if err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+	fmt.Print(out)
+}
+
+// looksLikePath reports whether arg is a path rather than a version spec
+func looksLikePath(arg string) bool {
+	return strings.ContainsAny(arg, `/\`) || strings.HasPrefix(arg, ".") || strings.HasPrefix(arg, "~")
+}
+
+// envGoroot returns the GOROOT to activate for a version spec: the current symlink for an
+// empty spec, and otherwise the installed toolchain that matches it
+func envGoroot(installPath, spec string) (string, error) {
+	if spec == "" {
+		if currentGoroot(installPath) == "" {
+			return "", fmt.Errorf("no default Go version in %s; run 'getgo use <version> %s' or pass a version", installPath, installPath)
+		}
+		return currentLinkPath(installPath), nil
+	}
+
+	goInst, err := resolveInstalled(installPath, spec)
+	if err != nil {
+		return "", fmt.Errorf("%v; install it with 'getgo %s %s'", err, spec, installPath)
+	}
+	return goInst.Dir, nil
+}
+
+// pathWithoutGo returns the entries of PATH without the bin directories of the toolchains under
+// installPath and of the GOROOT and GOPATH in the environment
+func pathWithoutGo(installPath string) []string {
+	var drop []string
+	if goroot := os.Getenv("GOROOT"); goroot != "" {
+		drop = append(drop, filepath.Join(goroot, "bin"))
+	}
+	for _, gopath := range filepath.SplitList(os.Getenv("GOPATH")) {
+		drop = append(drop, filepath.Join(gopath, "bin"))
+	}
+
+	var kept []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		clean := filepath.Clean(dir)
+		if filepath.Base(clean) == "bin" && filepath.Dir(filepath.Dir(clean)) == filepath.Clean(installPath) {
+			continue
+		}
+		if slices.Contains(drop, clean) {
+			continue
+		}
+		kept = append(kept, dir)
+	}
+	return kept
+}
+
+// activationChanges returns the changes that activate goroot and gopath
+func activationChanges(installPath, goroot, gopath string) []envChange {
+	path := append([]string{filepath.Join(goroot, "bin"), filepath.Join(gopath, "bin")}, pathWithoutGo(installPath)...)
+	return []envChange{
+		{name: "GOROOT", value: goroot},
+		{name: "GOPATH", value: gopath},
+		{name: "PATH", value: strings.Join(path, string(os.PathListSeparator)), list: path},
+	}
+}
+
+// unsetChanges returns the changes that undo an activation
+func unsetChanges(installPath string) []envChange {
+	path := pathWithoutGo(installPath)
+	return []envChange{
+		{name: "GOROOT", unset: true},
+		{name: "GOPATH", unset: true},
+		{name: "PATH", value: strings.Join(path, string(os.PathListSeparator)), list: path},
+	}
+}
+
+// renderEnv renders changes in the given output format, using the syntax of sh for "shell"
+func renderEnv(changes []envChange, format string, sh shell) (string, error) {
+	var sb strings.Builder
+	switch format {
+	case "shell":
+		for _, c := range changes {
+			switch {
+			case c.unset:
+				sb.WriteString(sh.unsetenv(c.name))
+			case c.list != nil:
+				sb.WriteString(sh.setPath(c.list))
+			default:
+				sb.WriteString(sh.setenv(c.name, c.value))
+			}
+			sb.WriteString("\n")
+		}
+
+	case "dotenv":
+		// dotenv has no way to unset a variable, so it is set to the empty string
+		for _, c := range changes {
+			value := c.value
+			if value != "" && !isShellSafe(value) {
+				value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+			}
+			fmt.Fprintf(&sb, "%s=%s\n", c.name, value)
+		}
+
+	case "json":
+		// Unset variables are null
+		vars := map[string]*string{}
+		for _, c := range changes {
+			if c.unset {
+				vars[c.name] = nil
+			} else {
+				vars[c.name] = &c.value
+			}
+		}
+		content, err := json.MarshalIndent(vars, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		sb.Write(append(content, '\n'))
+
+	case "make":
+		for _, c := range changes {
+			if c.unset {
+				fmt.Fprintf(&sb, "unexport %s\n", c.name)
+			} else {
+				fmt.Fprintf(&sb, "export %s := %s\n", c.name, strings.ReplaceAll(c.value, "$", "$$"))
+			}
+		}
+
+	default:
+		return "", fmt.Errorf("unknown format %q", format)
+	}
+	return sb.String(), nil
+}
+
+// updateShellConfig writes or removes the getgo block in shell configuration files. The block
+// points at the current symlink of the install root given in args, or the configured one.
+func updateShellConfig(home, file string, args []string, sh shell, gopath string, remove, dryRun bool) {
+	// Windows keeps the variables in the user environment rather than in a file
+	if runtime.GOOS == "windows" && file == "" {
+		color.Red("Error: on Windows, pass the file to edit with --file")
+		os.Exit(1)
+	}
+
+	var files []string
+	switch {
+	case file != "":
+		files = []string{expandPathOrExit(file)}
+	case remove:
+		files = shellConfigFiles(home)
+	default:
+		if files = []string{getShellConfigFile()}; files[0] == "" {
+			color.Red("Error: could not determine the shell configuration file; pass it with --file")
+			os.Exit(1)
+		}
+	}
+
+	goroot := ""
+	if !remove {
+		cfg, err := loadConfig()
+		if err != nil {
+			color.Red("Error: %v", err)
+			os.Exit(1)
+		}
+		if len(args) == 1 {
+			cfg.Root = expandPathOrExit(args[0])
+		}
+		if currentGoroot(cfg.Root) == "" {
+			color.Red("Error: no default Go version in %s; run 'getgo use <version> %s' first", cfg.Root, cfg.Root)
+			os.Exit(1)
+		}
+		goroot = currentLinkPath(cfg.Root)
+	}
+
+	changed := false
+	for _, file := range files {
+		// Files that do not exist have no block to remove
+		if _, err := os.Stat(file); os.IsNotExist(err) && remove {
+			continue
+		}
+
+		// Write the block in the syntax of the shell that reads the file, unless --shell says otherwise
+		block := ""
+		if !remove {
+			fileShell := sh
+			if fileShell == "" {
+				var ok bool
+				if fileShell, ok = shellForFile(file); !ok {
+					fileShell = detectShell()
+				}
+			}
+			block = rcBlock(fileShell.goEnv(goroot, gopath))
+		}
+
+		fileChanged, err := updateRCFile(file, block, dryRun)
+		if err != nil {
+			color.Red("Error updating %s: %v", file, err)
+			os.Exit(1)
+		}
+		if !fileChanged {
+			continue
+		}
+		changed = true
+
+		switch {
+		case dryRun:
+		case remove:
+			color.Green("Removed the getgo block from %s", file)
+		default:
+			color.Green("Updated the getgo block in %s", file)
+			warnRCAssignments(file)
+		}
+	}
+
+	switch {
+	case !changed && remove:
+		color.Yellow("No getgo block found in %s", strings.Join(files, ", "))
+	case !changed:
+		color.Green("%s is up to date", strings.Join(files, ", "))
+	case !dryRun:
+		color.Yellow("Restart your shell or source the file to apply the changes")
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// envrcSkipDirs are directories the .envrc check does not descend into
+var envrcSkipDirs = []string{"node_modules", "vendor"}
+
+// direnvLib is the direnv library that provides "use getgo"; %[1]s is the quoted path of getgo
+const direnvLib = `# direnv library for getgo, installed by 'getgo envrc --install-lib'
+#
+# Usage in .envrc:
+#
+#   use getgo <version> [install_path]
+#
+# Activates the installed Go toolchain matching version, e.g. "use getgo 1.22" for the
+# newest installed 1.22.x, without hard-coding its path.
+use_getgo() {
+  if [[ $# -lt 1 ]]; then
+    log_error "usage: use getgo <version> [install_path]"
+    return 1
+  fi
+  local env
+  env=$(%[1]s env --shell bash "$@") || return 1
+  eval "$env"
+}
+`
+
+// printEnvrcUsage prints the usage information for the envrc command
+func printEnvrcUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Printf("%s: getgo envrc --check [dir]\n", bold("Usage"))
+	fmt.Printf("       getgo envrc --install-lib\n")
+	fmt.Printf("%s:\n", bold("Examples"))
+	fmt.Printf("  %s      # Report stale getgo blocks in .envrc files below ~/src\n", cyan("getgo envrc --check ~/src"))
+	fmt.Printf("  %s      # Install the 'use getgo <version>' direnv helper\n", cyan("getgo envrc --install-lib"))
+
+	fmt.Printf("\n%s:\n", bold("Options"))
+	fmt.Printf("  --check            Report .envrc files whose getgo block or 'use getgo' line is out of date\n")
+	fmt.Printf("  --install-lib      Write the direnv library providing 'use getgo' to the direnv lib directory\n")
+
+	fmt.Printf("\nA block is stale if its GOROOT no longer exists, or if another installed toolchain is the one\n")
+	fmt.Printf("'getgo sync' would now pick for the project. The check exits with status 1 if any is stale.\n")
+}
+
+// runEnvrc checks .envrc files or installs the direnv library
+func runEnvrc(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("envrc", flag.ExitOnError)
+	fs.Usage = printEnvrcUsage
+	checkFlag := fs.Bool("check", false, "Report stale .envrc files")
+	installLibFlag := fs.Bool("install-lib", false, "Install the direnv library")
+	args = parseArgs(fs, args)
+
+	if *checkFlag == *installLibFlag || len(args) > 1 || (*installLibFlag && len(args) > 0) {
+		printEnvrcUsage()
+		os.Exit(1)
+	}
+
+	if *installLibFlag {
+		path, err := installDirenvLib()
+		if err != nil {
+			color.Red("Error installing the direnv library: %v", err)
+			os.Exit(1)
+		}
+		color.Green("Installed the direnv library at %s", path)
+		fmt.Println("Use it in a .envrc file with 'use getgo <version>'")
+		return
+	}
+
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	dir = expandPathOrExit(dir)
+	if _, err := os.Stat(dir); err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+
+	checked, stale := 0, 0
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped rather than ending the walk
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || slices.Contains(envrcSkipDirs, d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != ".envrc" {
+			return nil
+		}
+
+		problems, err := checkEnvrcFile(cfg, path)
+		if err != nil {
+			color.Red("%s: %v", path, err)
+			return nil
+		}
+		checked++
+		if len(problems) > 0 {
+			stale++
+		}
+		for _, problem := range problems {
+			color.Yellow("%s: %s", path, problem)
+		}
+		return nil
+	})
+	if err != nil {
+		color.Red("Error walking %s: %v", dir, err)
+		os.Exit(1)
+	}
+
+	switch {
+	case checked == 0:
+		fmt.Printf("No .envrc files found below %s\n", dir)
+	case stale == 0:
+		color.Green("%d .envrc files checked, all up to date", checked)
+	default:
+		color.Yellow("%d of %d .envrc files are stale", stale, checked)
+		os.Exit(1)
+	}
+}
+
+// envrcGoroot returns the GOROOT set by the getgo block of a .envrc file, or by the exports
+// older getgo versions appended, or "" if there is neither
+func envrcGoroot(content string) (string, error) {
+	lines := splitLines(content)
+	start, end, found, err := findRCBlock(lines)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		if start, end, found = findLegacyRCBlock(lines); !found {
+			return "", nil
+		}
+	}
+
+	for _, line := range lines[start : end+1] {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "export GOROOT="); ok {
+			return unquoteShell(value), nil
+		}
+	}
+	return "", nil
+}
+
+// unquoteShell removes the quotes shell.quote and older getgo versions put around a value
+func unquoteShell(value string) string {
+	switch {
+	case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.ReplaceAll(value[1:len(value)-1], `'\''`, "'")
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// checkEnvrcFile returns what is out of date in the .envrc file at path: a getgo block pointing
+// at a missing toolchain or at another one than sync would pick, or a 'use getgo' line that
+// matches no installed toolchain
+func checkEnvrcFile(cfg *Config, path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+
+	var problems []string
+	for _, line := range splitLines(string(content)) {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "use" || fields[1] != "getgo" {
+			continue
+		}
+		root := cfg.Root
+		if len(fields) > 3 {
+			if root, err = expandPath(fields[3]); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := resolveInstalled(root, fields[2]); err != nil {
+			problems = append(problems, fmt.Sprintf("'use getgo %s': %v", fields[2], err))
+		}
+	}
+
+	goroot, err := envrcGoroot(string(content))
+	if err != nil || goroot == "" {
+		return problems, err
+	}
+
+	version, err := readGoVersionFile(goroot)
+	if err != nil {
+		return append(problems, fmt.Sprintf("points to %s, which is no longer installed; run 'getgo sync %s'", goroot, dir)), nil
+	}
+
+	req, err := findVersionRequirement(dir, syncFiles)
+	if err != nil || req == nil {
+		// Without a project version there is nothing to compare against
+		return problems, err
+	}
+
+	// The toolchain sync would pick now, from the install root the block points into
+	want, err := matchInstalled(filepath.Dir(goroot), req)
+	switch {
+	case err != nil && !satisfies(version, req):
+		problems = append(problems, fmt.Sprintf("Go %s does not satisfy %s; run 'getgo sync %s'", version, req, dir))
+	case err == nil && !samePath(want.Dir, goroot):
+		problems = append(problems, fmt.Sprintf("points to Go %s, but Go %s is installed for %s; run 'getgo sync %s'",
+			version, want.Version, req, dir))
+	}
+	return problems, nil
+}
+
+// direnvLibPath returns where direnv loads libraries from
+func direnvLibPath() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return "", fmt.Errorf("error getting current user: %v", err)
+		}
+		configHome = filepath.Join(usr.HomeDir, ".config")
+	}
+	return filepath.Join(configHome, "direnv", "lib", "getgo.sh"), nil
+}
+
+// installDirenvLib writes the direnv library that provides "use getgo" and returns its path
+func installDirenvLib() (string, error) {
+	path, err := direnvLibPath()
+	if err != nil {
+		return "", err
+	}
+
+	// The library calls getgo by its full path, as .envrc files may run before PATH is set up
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "getgo"
+	}
+	if err := writeFileAtomic(path, []byte(fmt.Sprintf(direnvLib, shellBash.quote(exe)))); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "syscall"
+
+// execTool replaces the current process with the tool at path
+func execTool(path string, args, env []string) error {
+	return syscall.Exec(path, append([]string{path}, args...), env)
+}
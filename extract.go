@@ -0,0 +1,379 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// maxExtractSize caps the total size of the extracted files, as a guard against decompression
+	// bombs. A Go release unpacks to a few hundred MB.
+	maxExtractSize = 2 << 30
+
+	// maxExtractEntries caps the number of archive entries; a Go release has about 15,000
+	maxExtractEntries = 100000
+)
+
+// extractor writes archive entries below dst and refuses anything that would end up outside of it.
+// Symlinks are created last, so no entry can be written through one, and checked once they all exist.
+type extractor struct {
+	ctx      context.Context // Stops the extraction between entries when canceled
+	dst      string
+	written  int64                // Bytes written so far
+	entries  int                  // Entries extracted so far
+	dirTimes map[string]time.Time // Directory modification times, applied at the end
+	symlinks []pendingSymlink
+}
+
+// pendingSymlink is a symlink entry waiting for the end of the extraction
+type pendingSymlink struct {
+	name   string // Entry name, for messages
+	path   string
+	target string
+}
+
+// newExtractor returns an extractor for the directory dst, creating it if needed
+func newExtractor(ctx context.Context, dst string) (*extractor, error) {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return nil, err
+	}
+	dst, err := filepath.Abs(dst)
+	if err != nil {
+		return nil, err
+	}
+	return &extractor{ctx: ctx, dst: dst, dirTimes: map[string]time.Time{}}, nil
+}
+
+// path maps an archive entry name to its location below dst, rejecting absolute paths and
+// names that climb out with ".."
+func (e *extractor) path(name string) (string, error) {
+	clean := strings.TrimSuffix(name, "/")
+	if clean == "" || !filepath.IsLocal(filepath.FromSlash(clean)) {
+		return "", fmt.Errorf("illegal path in archive: %q", name)
+	}
+	return filepath.Join(e.dst, filepath.FromSlash(clean)), nil
+}
+
+// add counts an entry against the entry limit, and fails once the extraction is canceled
+func (e *extractor) add() error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	e.entries++
+	if e.entries > maxExtractEntries {
+		return fmt.Errorf("archive has more than %d entries", maxExtractEntries)
+	}
+	return nil
+}
+
+// replace prepares path for a new entry, removing whatever an earlier entry left there
+func replace(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// mkdir creates a directory entry
+func (e *extractor) mkdir(name string, mtime time.Time) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	if err := e.add(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	e.dirTimes[path] = mtime
+	return nil
+}
+
+// writeFile creates a regular file entry with the content of r
+func (e *extractor) writeFile(name string, r io.Reader, size int64, mode os.FileMode, mtime time.Time) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	if err := e.add(); err != nil {
+		return err
+	}
+	if size > maxExtractSize-e.written {
+		return fmt.Errorf("archive is larger than %s when extracted", formatSize(maxExtractSize))
+	}
+	if err := replace(path); err != nil {
+		return err
+	}
+
+	// O_EXCL makes sure we never write through a file that appeared in the meantime
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+
+	// Do not trust the declared size, stop as soon as the limit is crossed
+	n, err := io.Copy(out, io.LimitReader(r, maxExtractSize-e.written+1))
+	e.written += n
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if e.written > maxExtractSize {
+		return fmt.Errorf("archive is larger than %s when extracted", formatSize(maxExtractSize))
+	}
+
+	// Apply the exact mode regardless of the umask
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	return os.Chtimes(path, mtime, mtime)
+}
+
+// link creates a hard link entry to a file extracted earlier
+func (e *extractor) link(name, target string) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	oldPath, err := e.path(target)
+	if err != nil {
+		return fmt.Errorf("hard link %s: %v", name, err)
+	}
+	if err := e.add(); err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(oldPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("hard link %s points to %s, which is not a file extracted before it", name, target)
+	}
+	if err := replace(path); err != nil {
+		return err
+	}
+	return os.Link(oldPath, path)
+}
+
+// symlink records a symlink entry, which is created by finish
+func (e *extractor) symlink(name, target string) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	if err := e.add(); err != nil {
+		return err
+	}
+	if target == "" || filepath.IsAbs(target) || strings.HasPrefix(target, "/") {
+		return fmt.Errorf("symlink %s points outside of the archive: %q", name, target)
+	}
+	if rel, err := filepath.Rel(e.dst, filepath.Join(filepath.Dir(path), filepath.FromSlash(target))); err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return fmt.Errorf("symlink %s points outside of the archive: %q", name, target)
+	}
+	e.symlinks = append(e.symlinks, pendingSymlink{name: name, path: path, target: target})
+	return nil
+}
+
+// finish creates the symlinks, checks where they lead and applies the directory modification times
+func (e *extractor) finish() error {
+	for _, link := range e.symlinks {
+		// A symlink created earlier must not redirect where this one is created
+		if err := e.checkParents(link.name, link.path); err != nil {
+			return err
+		}
+		if err := replace(link.path); err != nil {
+			return err
+		}
+		if err := os.Symlink(filepath.FromSlash(link.target), link.path); err != nil {
+			return err
+		}
+	}
+	for _, link := range e.symlinks {
+		if err := e.checkSymlink(link); err != nil {
+			return err
+		}
+	}
+
+	// Directories last, as creating their contents changed their times
+	for path, mtime := range e.dirTimes {
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkParents verifies that no directory between dst and the path of entry name is a symlink
+func (e *extractor) checkParents(name, path string) error {
+	dir := filepath.Dir(path)
+	for dir != e.dst && len(dir) > len(e.dst) {
+		info, err := os.Lstat(dir)
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to extract %s through a symlink", name)
+		}
+		dir = filepath.Dir(dir)
+	}
+	return nil
+}
+
+// checkSymlink verifies that a symlink resolves inside dst. Every directory on the way to the
+// target must be a real directory rather than another symlink, so that the OS resolves the
+// target exactly as it is checked here.
+func (e *extractor) checkSymlink(link pendingSymlink) error {
+	rel, err := filepath.Rel(e.dst, filepath.Dir(link.path))
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	parts = append(parts, strings.Split(filepath.ToSlash(filepath.FromSlash(link.target)), "/")...)
+
+	var resolved []string
+	for i, part := range parts {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return fmt.Errorf("symlink %s points outside of the archive: %q", link.name, link.target)
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+		resolved = append(resolved, part)
+
+		// The target itself may be another symlink, which is checked on its own
+		if i == len(parts)-1 {
+			break
+		}
+		info, err := os.Lstat(filepath.Join(e.dst, filepath.Join(resolved...)))
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("symlink %s resolves through another symlink: %q", link.name, link.target)
+		}
+	}
+	return nil
+}
+
+// untargz extracts the .tar.gz archive src into dst
+func untargz(ctx context.Context, src, dst string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return extractTarGz(ctx, file, dst)
+}
+
+// extractTarGz extracts a gzip-compressed tar stream into dst
+func extractTarGz(ctx context.Context, r io.Reader, dst string) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	e, err := newExtractor(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = e.mkdir(header.Name, header.ModTime)
+		case tar.TypeReg:
+			err = e.writeFile(header.Name, tr, header.Size, os.FileMode(header.Mode).Perm(), header.ModTime)
+		case tar.TypeLink:
+			err = e.link(header.Name, header.Linkname)
+		case tar.TypeSymlink:
+			err = e.symlink(header.Name, header.Linkname)
+		case tar.TypeXGlobalHeader:
+			// pax global headers only carry metadata
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			err = fmt.Errorf("refusing to extract device or FIFO %s", header.Name)
+		default:
+			err = fmt.Errorf("unsupported entry type %q for %s", header.Typeflag, header.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.finish()
+}
+
+// unzip extracts the .zip archive src into dst
+func unzip(ctx context.Context, src, dst string) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	e, err := newExtractor(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range r.File {
+		if err := extractZipEntry(e, f, f.Name, f.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return e.finish()
+}
+
+// extractZipEntry extracts a zip entry under the given name, with perm as the mode of regular files
+func extractZipEntry(e *extractor, f *zip.File, name string, perm os.FileMode) error {
+	mode := f.Mode()
+	switch {
+	case mode.IsDir() || strings.HasSuffix(name, "/"):
+		return e.mkdir(name, f.Modified)
+	case mode&os.ModeSymlink != 0:
+		// The symlink target is stored as the entry's content
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		target, err := io.ReadAll(io.LimitReader(rc, 4096))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		return e.symlink(name, string(target))
+	case mode&(os.ModeDevice|os.ModeCharDevice|os.ModeNamedPipe|os.ModeSocket) != 0:
+		return fmt.Errorf("refusing to extract device or FIFO %s", name)
+	}
+
+	// Zip files written on Windows may not record permissions
+	if perm == 0 {
+		perm = 0644
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return e.writeFile(name, rc, int64(f.UncompressedSize64), perm, f.Modified)
+}
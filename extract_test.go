@@ -0,0 +1,327 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEntry is an archive entry for the extraction tests
+type testEntry struct {
+	name string
+	typ  byte   // tar.TypeReg, tar.TypeDir, tar.TypeSymlink or tar.TypeLink (tar only)
+	body string // File content, or the target of a link
+	size int64  // Declared size of a regular file if not len(body); the body is then not written
+}
+
+// writeTestTarGz writes the entries as a .tar.gz archive and returns its path
+func writeTestTarGz(t *testing.T, entries []testEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+
+	truncated := false
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Typeflag: entry.typ, Mode: 0644}
+		switch entry.typ {
+		case tar.TypeDir:
+			header.Mode = 0755
+		case tar.TypeSymlink, tar.TypeLink:
+			header.Linkname = entry.body
+		case tar.TypeReg:
+			header.Size = int64(len(entry.body))
+			if entry.size > 0 {
+				header.Size = entry.size
+			}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if entry.size > 0 {
+			// The content is never read, as the declared size alone is over the limit
+			truncated = true
+			break
+		}
+		if entry.typ != tar.TypeReg {
+			continue
+		}
+		if _, err := tw.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !truncated {
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	} else if err := tw.Flush(); err != nil && !strings.Contains(err.Error(), "missed writing") {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeTestZip writes the entries as a .zip archive and returns its path
+func writeTestZip(t *testing.T, entries []testEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Store}
+		switch entry.typ {
+		case tar.TypeDir:
+			header.Name = strings.TrimSuffix(entry.name, "/") + "/"
+			header.SetMode(os.ModeDir | 0755)
+		case tar.TypeSymlink:
+			header.SetMode(os.ModeSymlink | 0777)
+		case tar.TypeReg:
+			header.SetMode(0644)
+		default:
+			t.Fatalf("zip archives have no entries of type %q", entry.typ)
+		}
+
+		if entry.size > 0 {
+			// Declare a size the data does not have; it is never read
+			header.UncompressedSize64 = uint64(entry.size)
+			if _, err := zw.CreateRaw(header); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// extractFormats runs an extraction test for both archive formats
+var extractFormats = []struct {
+	name    string
+	write   func(*testing.T, []testEntry) string
+	extract func(context.Context, string, string) error
+}{
+	{"tar.gz", writeTestTarGz, untargz},
+	{"zip", writeTestZip, unzip},
+}
+
+func TestExtract(t *testing.T) {
+	entries := []testEntry{
+		{name: "go/", typ: tar.TypeDir},
+		{name: "go/VERSION", typ: tar.TypeReg, body: "go1.22.3\n"},
+		{name: "go/bin/", typ: tar.TypeDir},
+		{name: "go/bin/go", typ: tar.TypeReg, body: "binary"},
+		{name: "go/misc/wasm", typ: tar.TypeSymlink, body: "../lib/wasm"},
+		{name: "go/lib/wasm/", typ: tar.TypeDir},
+		{name: "go/lib/wasm/go_js_wasm_exec", typ: tar.TypeReg, body: "script"},
+	}
+	for _, format := range extractFormats {
+		t.Run(format.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "dst")
+			if err := format.extract(context.Background(), format.write(t, entries), dst); err != nil {
+				t.Fatalf("extract: %v", err)
+			}
+			if got, err := os.ReadFile(filepath.Join(dst, "go", "VERSION")); err != nil || string(got) != "go1.22.3\n" {
+				t.Errorf("go/VERSION = %q, %v", got, err)
+			}
+			if got, err := os.ReadFile(filepath.Join(dst, "go", "misc", "wasm", "go_js_wasm_exec")); err != nil || string(got) != "script" {
+				t.Errorf("reading through go/misc/wasm = %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestExtractRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+		wantErr string
+	}{
+		{
+			name:    "dot-dot entry",
+			entries: []testEntry{{name: "go/../../evil", typ: tar.TypeReg, body: "x"}},
+			wantErr: "illegal path",
+		},
+		{
+			name:    "absolute name",
+			entries: []testEntry{{name: "/tmp/evil", typ: tar.TypeReg, body: "x"}},
+			wantErr: "illegal path",
+		},
+		{
+			name:    "dot-dot directory",
+			entries: []testEntry{{name: "../evil/", typ: tar.TypeDir}},
+			wantErr: "illegal path",
+		},
+		{
+			name:    "symlink climbing out",
+			entries: []testEntry{{name: "go/escape", typ: tar.TypeSymlink, body: "../../evil"}},
+			wantErr: "points outside of the archive",
+		},
+		{
+			name:    "absolute symlink",
+			entries: []testEntry{{name: "go/escape", typ: tar.TypeSymlink, body: "/etc"}},
+			wantErr: "points outside of the archive",
+		},
+		{
+			// The link itself looks harmless, but its parent is a symlink created earlier
+			name: "symlink below a symlink",
+			entries: []testEntry{
+				{name: "go/real/", typ: tar.TypeDir},
+				{name: "go/alias", typ: tar.TypeSymlink, body: "real"},
+				{name: "go/alias/evil", typ: tar.TypeSymlink, body: "x"},
+			},
+			wantErr: "refusing to extract go/alias/evil through a symlink",
+		},
+		{
+			// go/up points at go, so go/up/.. would be checked as go but resolved by the OS as dst/..
+			name: "symlink through a symlink",
+			entries: []testEntry{
+				{name: "go/sub/", typ: tar.TypeDir},
+				{name: "go/sub/up", typ: tar.TypeSymlink, body: ".."},
+				{name: "go/escape", typ: tar.TypeSymlink, body: "sub/up/.."},
+			},
+			wantErr: "resolves through another symlink",
+		},
+		{
+			name:    "declared size over the cap",
+			entries: []testEntry{{name: "go/bomb", typ: tar.TypeReg, size: maxExtractSize + 1}},
+			wantErr: "larger than",
+		},
+	}
+	for _, tt := range tests {
+		for _, format := range extractFormats {
+			t.Run(tt.name+"/"+format.name, func(t *testing.T) {
+				dir := t.TempDir()
+				dst := filepath.Join(dir, "dst")
+				err := format.extract(context.Background(), format.write(t, tt.entries), dst)
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extract error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Lstat(filepath.Join(dir, "evil")); err == nil {
+					t.Errorf("extraction wrote %s outside of dst", filepath.Join(dir, "evil"))
+				}
+			})
+		}
+	}
+}
+
+func TestExtractHardLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+		wantErr string
+	}{
+		{
+			name: "valid",
+			entries: []testEntry{
+				{name: "go/bin/go", typ: tar.TypeReg, body: "binary"},
+				{name: "go/pkg/tool/go", typ: tar.TypeLink, body: "go/bin/go"},
+			},
+		},
+		{
+			name:    "missing target",
+			entries: []testEntry{{name: "go/link", typ: tar.TypeLink, body: "go/missing"}},
+			wantErr: "not a file extracted before it",
+		},
+		{
+			name: "directory target",
+			entries: []testEntry{
+				{name: "go/dir/", typ: tar.TypeDir},
+				{name: "go/link", typ: tar.TypeLink, body: "go/dir"},
+			},
+			wantErr: "not a file extracted before it",
+		},
+		{
+			// Symlinks only exist once everything else is extracted, so this finds nothing
+			name: "symlink target",
+			entries: []testEntry{
+				{name: "go/sym", typ: tar.TypeSymlink, body: "VERSION"},
+				{name: "go/VERSION", typ: tar.TypeReg, body: "go1.22.3"},
+				{name: "go/link", typ: tar.TypeLink, body: "go/sym"},
+			},
+			wantErr: "not a file extracted before it",
+		},
+		{
+			name:    "target outside",
+			entries: []testEntry{{name: "go/link", typ: tar.TypeLink, body: "../../etc/passwd"}},
+			wantErr: "illegal path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "dst")
+			err := untargz(context.Background(), writeTestTarGz(t, tt.entries), dst)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("untargz: %v", err)
+				}
+				if got, err := os.ReadFile(filepath.Join(dst, "go", "pkg", "tool", "go")); err != nil || string(got) != "binary" {
+					t.Errorf("hard link content = %q, %v", got, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("untargz error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtractEntryLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes archives with more than 100,000 entries")
+	}
+	// The same directory over and over keeps the archive small and the extraction fast
+	entries := make([]testEntry, maxExtractEntries+1)
+	for i := range entries {
+		entries[i] = testEntry{name: "go/", typ: tar.TypeDir}
+	}
+	for _, format := range extractFormats {
+		t.Run(format.name, func(t *testing.T) {
+			err := format.extract(context.Background(), format.write(t, entries), filepath.Join(t.TempDir(), "dst"))
+			if err == nil || !strings.Contains(err.Error(), "more than") {
+				t.Fatalf("extract error = %v, want the entry limit", err)
+			}
+		})
+	}
+}
+
+func TestExtractSizeLimit(t *testing.T) {
+	// A file that declares less than it holds is cut off at the limit; starting close to the
+	// limit avoids writing 2 GiB
+	e, err := newExtractor(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.written = maxExtractSize - 10
+	err = e.writeFile("go/bomb", strings.NewReader(strings.Repeat("x", 100)), 5, 0644, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("writeFile error = %v, want the size limit", err)
+	}
+}
@@ -2,10 +2,13 @@ module getgo
 
 go 1.24.1
 
-require github.com/fatih/color v1.18.0
+require (
+	github.com/fatih/color v1.18.0
+	golang.org/x/mod v0.30.0
+	golang.org/x/sys v0.31.0
+)
 
 require (
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.31.0 // indirect
 )
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// hookShells lists the shells getgo hook prints a hook for
+var hookShells = []shell{shellBash, shellZsh, shellFish}
+
+// Variables the hook keeps its state in, exported so nested shells inherit it
+const (
+	hookGorootVar      = "GETGO_HOOK_GOROOT"      // GOROOT the hook activated
+	hookPrevGorootVar  = "GETGO_HOOK_PREV_GOROOT" // GOROOT before the hook changed it
+	hookRequirementVar = "GETGO_HOOK_REQUIREMENT" // Requirement the active GOROOT was looked up for
+)
+
+// hookScripts holds the hook of each shell; %[1]s is the quoted path of getgo. The hooks only
+// call getgo when the working directory changed, so prompts stay fast.
+var hookScripts = map[shell]string{
+	shellBash: `_getgo_hook() {
+  local previous_exit_status=$?
+  if [[ "$PWD" != "${_GETGO_HOOK_PWD-}" ]]; then
+    _GETGO_HOOK_PWD=$PWD
+    eval "$(%[1]s hook-env bash)"
+  fi
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_getgo_hook;"* ]]; then
+  PROMPT_COMMAND="_getgo_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`,
+	shellZsh: `_getgo_hook() {
+  eval "$(%[1]s hook-env zsh)"
+}
+typeset -ag chpwd_functions
+if [[ -z "${chpwd_functions[(r)_getgo_hook]+1}" ]]; then
+  chpwd_functions=(_getgo_hook $chpwd_functions)
+fi
+_getgo_hook
+`,
+	shellFish: `function __getgo_hook --on-variable PWD --description 'Switch Go versions on directory change'
+    %[1]s hook-env fish | source
+end
+__getgo_hook
+`,
+}
+
+// printHookUsage prints the usage information for the hook command
+func printHookUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Printf("%s: getgo hook <bash|zsh|fish>\n", bold("Usage"))
+	fmt.Printf("%s:\n", bold("Setup"))
+	fmt.Printf("  %s        # Add to ~/.bashrc\n", cyan(`eval "$(getgo hook bash)"`))
+	fmt.Printf("  %s         # Add to ~/.zshrc\n", cyan(`eval "$(getgo hook zsh)"`))
+	fmt.Printf("  %s        # Add to ~/.config/fish/config.fish\n", cyan("getgo hook fish | source"))
+	fmt.Printf("\nOn each directory change, the hook looks for the same project files as the shims (by default\n")
+	fmt.Printf(".go-version, go.work and go.mod) and points GOROOT and PATH at the matching toolchain in the\n")
+	fmt.Printf("configured root. Leaving the project restores the previous GOROOT and PATH.\n")
+}
+
+// runHook prints the shell code that runs hook-env on directory changes
+func runHook(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("hook", flag.ExitOnError)
+	fs.Usage = printHookUsage
+	args = parseArgs(fs, args)
+
+	if len(args) != 1 {
+		printHookUsage()
+		os.Exit(1)
+	}
+	sh, ok := parseShell(args[0])
+	if !ok || !slices.Contains(hookShells, sh) {
+		color.Red("Error: no hook for %q (supported: bash, zsh, fish)", args[0])
+		os.Exit(1)
+	}
+
+	// The hook calls getgo by its full path, so it works even before PATH is set up
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "getgo"
+	}
+	fmt.Printf(hookScripts[sh], sh.quote(exe))
+}
+
+// runHookEnv prints the changes that activate the toolchain of the working directory, or undo
+// the previous activation outside a project. It is run by the shell hooks and not listed in the usage.
+func runHookEnv(_ context.Context, args []string) {
+	// The output is evaluated by the shell, so diagnostics go to stderr
+	color.Output = color.Error
+
+	if len(args) != 1 {
+		color.Red("Usage: getgo hook-env <shell>")
+		os.Exit(1)
+	}
+	sh, ok := parseShell(args[0])
+	if !ok {
+		color.Red("getgo: unknown shell %q", args[0])
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		color.Red("getgo: %v", err)
+		os.Exit(1)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		color.Red("getgo: %v", err)
+		os.Exit(1)
+	}
+
+	out, err := renderEnv(hookChanges(cfg, dir), "shell", sh)
+	if err != nil {
+		color.Red("getgo: %v", err)
+		os.Exit(1)
+	}
+	fmt.Print(out)
+}
+
+// setOrUnset returns the change that sets name to value, or unsets it if value is empty
+func setOrUnset(name, value string) envChange {
+	return envChange{name: name, value: value, unset: value == ""}
+}
+
+// hookChanges returns the changes that switch the environment to the toolchain required in dir.
+// Only the PATH entry the hook added is replaced, and GOROOT is only restored if nothing else
+// changed it since.
+func hookChanges(cfg *Config, dir string) []envChange {
+	req, err := findVersionRequirement(dir, cfg.ShimOrder)
+	if err != nil {
+		color.Yellow("getgo: %v", err)
+		return nil
+	}
+
+	// The toolchain is only looked up again when the requirement changed
+	key := ""
+	if req != nil {
+		key = req.String()
+	}
+	if key == os.Getenv(hookRequirementVar) {
+		return nil
+	}
+
+	target := ""
+	if req != nil {
+		goInst, err := matchInstalled(cfg.Root, req)
+		if err != nil {
+			color.Yellow("getgo: %s is not installed in %s; run 'getgo sync' to install it", req, cfg.Root)
+			// Look it up again on the next directory change, as it may have been installed by then
+			key = ""
+		} else {
+			target = goInst.Dir
+		}
+	}
+
+	active := os.Getenv(hookGorootVar)
+	changes := []envChange{setOrUnset(hookRequirementVar, key)}
+	if target == active || samePath(target, active) {
+		return changes
+	}
+
+	path := filepath.SplitList(os.Getenv("PATH"))
+	if active != "" {
+		path = slices.DeleteFunc(path, func(p string) bool {
+			return filepath.Clean(p) == filepath.Join(active, "bin")
+		})
+	}
+
+	goroot := os.Getenv("GOROOT")
+	if target != "" {
+		if active == "" {
+			changes = append(changes, setOrUnset(hookPrevGorootVar, goroot))
+		}
+		changes = append(changes,
+			envChange{name: "GOROOT", value: target},
+			envChange{name: hookGorootVar, value: target})
+		path = append([]string{filepath.Join(target, "bin")}, path...)
+	} else {
+		if goroot == active {
+			changes = append(changes, setOrUnset("GOROOT", os.Getenv(hookPrevGorootVar)))
+		}
+		changes = append(changes,
+			envChange{name: hookGorootVar, unset: true},
+			envChange{name: hookPrevGorootVar, unset: true})
+	}
+	return append(changes, envChange{name: "PATH", value: strings.Join(path, string(os.PathListSeparator)), list: path})
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// installOptions describes a single toolchain installation
+type installOptions struct {
+	versionSpec string // Version spec to resolve, e.g. "latest", "1.22" or "1.22.3"
+	installPath string // Absolute install root that receives go<version>
+	noVerify    bool   // Skip checksum verification against the release manifest
+	fromArchive string // Local path or URL of a release archive to install instead of resolving versionSpec
+	sha256      string // Expected SHA-256 of fromArchive, if known
+	stream      bool   // Extract .tar.gz archives while downloading instead of saving them first
+	force       bool   // Reinstall a version that is already installed
+	osName      string // Target operating system, empty for the host's
+	arch        string // Target architecture, empty for the host's
+}
+
+// platform returns the platform to install for and whether it differs from the host's
+func (opts installOptions) platform() (osName, arch string, foreign bool) {
+	hostOS, hostArch := hostPlatform()
+	osName, arch = hostOS, hostArch
+	if opts.osName != "" {
+		osName = opts.osName
+	}
+	if opts.arch != "" {
+		arch = goArch(opts.arch)
+	}
+	return osName, arch, osName != hostOS || arch != hostArch
+}
+
+// toolchainDirName returns the directory name of a toolchain in the install root: go<version>,
+// or go<version>.<os>-<arch> for a toolchain of another platform
+func toolchainDirName(version, osName, arch string, foreign bool) string {
+	if foreign {
+		return fmt.Sprintf("go%s.%s-%s", version, osName, arch)
+	}
+	return "go" + version
+}
+
+// resolveGoVersion resolves a version spec against the release manifest and returns the
+// concrete version together with the manifest. Only an exact version with verification
+// disabled skips the manifest, in which case the returned manifest is nil.
+func resolveGoVersion(ctx context.Context, spec string, noVerify bool) (string, []GoVersion, error) {
+	version := strings.TrimPrefix(spec, "go")
+
+	// Offline installs need the manifest to find archives in the cache
+	if noVerify && isConcreteVersion(spec) && !offline {
+		return version, nil, nil
+	}
+
+	color.Cyan("Fetching release manifest...")
+	versions, err := loadReleases(ctx)
+	if err != nil {
+		if offline || ctx.Err() != nil {
+			return "", nil, err
+		}
+		if isConcreteVersion(spec) {
+			return "", nil, fmt.Errorf("error fetching release manifest: %v (use --no-verify to skip checksum verification)", err)
+		}
+		return "", nil, fmt.Errorf("error fetching release manifest: %v", err)
+	}
+
+	resolved, err := resolveVersion(spec, versions)
+	if err != nil {
+		return "", nil, fmt.Errorf("error resolving Go version: %v", err)
+	}
+	if resolved != version {
+		color.Green("Resolved %s to Go %s", spec, resolved)
+	}
+	return resolved, versions, nil
+}
+
+// installGo resolves, downloads, verifies and extracts a Go toolchain into the install root
+// and returns its versioned directory. A version that is already installed is returned as is.
+// Canceling ctx stops the install and removes everything it staged.
+func installGo(ctx context.Context, opts installOptions) (string, error) {
+	if opts.fromArchive != "" {
+		return installFromArchive(ctx, opts)
+	}
+
+	source, err := downloadSource()
+	if err != nil {
+		return "", err
+	}
+
+	version, versions, err := resolveGoVersion(ctx, opts.versionSpec, opts.noVerify)
+	if err != nil {
+		return "", err
+	}
+
+	// Install for the machine rather than for the platform getgo was built for, unless told otherwise
+	osName, arch, foreign := opts.platform()
+	if foreign {
+		color.Cyan("Installing Go %s for %s/%s", version, osName, arch)
+	} else if osName != runtime.GOOS || arch != runtime.GOARCH {
+		color.Cyan("Detected a %s/%s host (getgo was built for %s/%s)", osName, arch, runtime.GOOS, runtime.GOARCH)
+	}
+
+	// Check if the version already exists at the destination
+	versionedGoDir, err := expandPath(filepath.Join(opts.installPath, toolchainDirName(version, osName, arch, foreign)))
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(versionedGoDir); err == nil && !opts.force {
+		color.Yellow("Go version %s already exists at %s", version, versionedGoDir)
+		return versionedGoDir, nil
+	}
+
+	// Stage next to the destination, so an interrupted install never leaves a partial tree behind
+	stageDir, err := newStagingDir(filepath.Dir(versionedGoDir))
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stageDir)
+
+	// Fetch the toolchain into stageDir/go, from GOPROXY or as a release archive
+	err = errDirect
+	if source == sourceProxy && !offline {
+		err = installFromProxy(ctx, version, osName, arch, opts.noVerify, stageDir)
+	}
+	if errors.Is(err, errDirect) {
+		err = installFromMirrors(ctx, version, osName, arch, versions, opts, stageDir)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Swap the extracted "go" directory into place, replacing an existing installation
+	if err := swapIntoPlace(ctx, filepath.Join(stageDir, "go"), versionedGoDir); err != nil {
+		return "", err
+	}
+
+	color.Green("Go %s has been successfully installed to %s", version, versionedGoDir)
+	return versionedGoDir, nil
+}
+
+// installFromArchive installs a release archive from a local file or URL. The version is read
+// from the archive's go/VERSION file, so the archive may have any name.
+func installFromArchive(ctx context.Context, opts installOptions) (string, error) {
+	installPath, err := expandPath(opts.installPath)
+	if err != nil {
+		return "", err
+	}
+
+	// The archive is downloaded and extracted next to the destination, see installGo
+	stageDir, err := newStagingDir(installPath)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stageDir)
+
+	archivePath := opts.fromArchive
+	isURL := strings.HasPrefix(archivePath, "http://") || strings.HasPrefix(archivePath, "https://")
+
+	var file *GoFile
+	switch {
+	case opts.sha256 != "":
+		file = &GoFile{Filename: filepath.Base(opts.fromArchive), SHA256: opts.sha256}
+	case !opts.noVerify:
+		// Archives are never installed unverified by accident: without --sha256, the archive
+		// has to be a release file listed in the manifest
+		name := filepath.Base(archivePath)
+		if isURL {
+			u, err := url.Parse(archivePath)
+			if err != nil {
+				return "", fmt.Errorf("invalid archive URL %s: %v", archivePath, err)
+			}
+			name = path.Base(u.Path)
+		}
+		if file, err = manifestFile(ctx, name); err != nil {
+			return "", err
+		}
+	}
+
+	if isURL {
+		if offline {
+			return "", fmt.Errorf("offline: cannot download %s", archivePath)
+		}
+		color.Cyan("Downloading %s...", archivePath)
+		archivePath = filepath.Join(stageDir, "archive")
+		if err := downloadFileWithProgress(ctx, opts.fromArchive, archivePath, maxDownloadAttempts); err != nil {
+			return "", fmt.Errorf("error downloading Go archive: %v", err)
+		}
+		fmt.Println() // Add a newline after progress bar
+	} else if archivePath, err = expandPath(archivePath); err != nil {
+		return "", err
+	}
+
+	if file != nil {
+		if err := verifyArchive(archivePath, file); err != nil {
+			return "", err
+		}
+		color.Green("Checksum verified: %s", file.SHA256)
+	} else {
+		color.Yellow("Skipping checksum verification of %s", opts.fromArchive)
+	}
+
+	// Archives are recognized by their content rather than their name
+	format, err := archiveFormat(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	color.Cyan("Extracting %s ...", opts.fromArchive)
+	extractDir := filepath.Join(stageDir, "extract")
+	if format == "zip" {
+		err = unzip(ctx, archivePath, extractDir)
+	} else {
+		err = untargz(ctx, archivePath, extractDir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error extracting archive: %v", err)
+	}
+
+	extractedGoDir := filepath.Join(extractDir, "go")
+	version, err := readGoVersionFile(extractedGoDir)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a Go release archive: %v", opts.fromArchive, err)
+	}
+	osName, arch, foreign := opts.platform()
+	if err := checkArchivePlatform(extractedGoDir, osName, arch); err != nil {
+		return "", err
+	}
+
+	versionedGoDir := filepath.Join(installPath, toolchainDirName(version, osName, arch, foreign))
+	if _, err := os.Stat(versionedGoDir); err == nil && !opts.force {
+		color.Yellow("Go version %s already exists at %s", version, versionedGoDir)
+		return versionedGoDir, nil
+	}
+
+	if err := swapIntoPlace(ctx, extractedGoDir, versionedGoDir); err != nil {
+		return "", err
+	}
+
+	color.Green("Go %s has been successfully installed to %s", version, versionedGoDir)
+	return versionedGoDir, nil
+}
+
+// manifestFile returns the release manifest entry of the archive file name. The cached manifest
+// is used offline, so archives copied to an air-gapped machine can be verified too.
+func manifestFile(ctx context.Context, name string) (*GoFile, error) {
+	versions, err := loadManifest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot verify %s without the release manifest: %v (pass --sha256, or --no-verify to skip verification)", name, err)
+	}
+	file := findArchiveByName(versions, name)
+	if file == nil {
+		return nil, fmt.Errorf("%s is not in the release manifest; pass its --sha256, or --no-verify to install it unverified", name)
+	}
+	color.Cyan("Verifying %s against the release manifest", name)
+	return file, nil
+}
+
+// archiveFormat detects whether path is a zip or a gzip-compressed tar archive
+func archiveFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// A file shorter than the magic numbers is simply not an archive
+	magic := make([]byte, 4)
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("error reading %s: %v", path, err)
+	}
+	switch {
+	case bytes.HasPrefix(magic[:n], []byte("PK\x03\x04")):
+		return "zip", nil
+	case bytes.HasPrefix(magic[:n], []byte{0x1f, 0x8b}):
+		return "tar.gz", nil
+	}
+	return "", fmt.Errorf("%s is neither a .tar.gz nor a .zip archive", path)
+}
+
+// checkArchivePlatform refuses a Go tree built for another platform than the one installed for
+func checkArchivePlatform(goroot, osName, arch string) error {
+	entries, err := os.ReadDir(filepath.Join(goroot, "pkg", "tool"))
+	if err != nil {
+		// Nothing to go by
+		return nil
+	}
+
+	want := osName + "_" + arch
+	var platforms []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if entry.Name() == want {
+			return nil
+		}
+		platforms = append(platforms, strings.Replace(entry.Name(), "_", "/", 1))
+	}
+	if len(platforms) == 0 {
+		return nil
+	}
+	return fmt.Errorf("archive contains Go for %s, not %s/%s (use --os and --arch to install it for another platform)", strings.Join(platforms, ", "), osName, arch)
+}
+
+// installFromMirrors downloads and verifies the release archive, or takes it from the cache,
+// and extracts it into destDir
+func installFromMirrors(ctx context.Context, version, osName, arch string, versions []GoVersion, opts installOptions, destDir string) error {
+	var archiveExt string
+	if osName == "windows" {
+		archiveExt = "zip"
+	} else {
+		archiveExt = "tar.gz"
+	}
+
+	// Look up the expected checksum before downloading anything
+	var archiveFile *GoFile
+	if !opts.noVerify || offline {
+		var err error
+		archiveFile, err = findArchive(versions, version, osName, arch)
+
+		// Releases listed by a GOPROXY carry no checksums, those come from the release manifest
+		if err == nil && archiveFile.SHA256 == "" {
+			if versions, err = loadManifest(ctx); err == nil {
+				archiveFile, err = findArchive(versions, version, osName, arch)
+			}
+		}
+		if err != nil {
+			// A release without the platform is explained already, a missing one may be the mirror's fault
+			if slices.ContainsFunc(versions, func(v GoVersion) bool { return v.Version == "go"+version }) {
+				return err
+			}
+			return fmt.Errorf("%v (check that the version exists at %s)", err, strings.Join(downloadMirrors(), ", "))
+		}
+	}
+
+	archiveName := fmt.Sprintf("go%s.%s-%s.%s", version, osName, releaseArch(arch), archiveExt)
+
+	// Stream a .tar.gz archive straight into destDir, unless it is cached already
+	if opts.stream && archiveExt == "tar.gz" && !offline && !isArchiveCached(archiveFile) {
+		color.Cyan("Streaming %s...", archiveName)
+		err := streamFromMirrors(ctx, archiveName, archiveFile, destDir)
+		if err == nil {
+			if archiveFile == nil {
+				color.Yellow("Skipping checksum verification")
+			} else {
+				color.Green("Checksum verified: %s", archiveFile.SHA256)
+			}
+			return nil
+		}
+
+		// A bad archive is an error, anything else but an interruption falls back to a regular download
+		var mismatch *checksumError
+		if errors.As(err, &mismatch) {
+			return fmt.Errorf("error verifying Go archive: %v", err)
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		color.Yellow("Streaming failed: %v", err)
+		color.Yellow("Falling back to downloading the archive")
+	}
+
+	// Download the Go archive, or take it from the cache
+	archivePath, cached, err := fetchArchive(ctx, archiveName, archiveFile, destDir)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("Go version %s not found for %s/%s (check that the version exists at %s)", version, osName, arch, strings.Join(downloadMirrors(), ", "))
+		}
+		return fmt.Errorf("error downloading Go archive: %v", err)
+	}
+
+	// Clean up an uncached archive once it has been extracted
+	if !cached {
+		defer os.Remove(archivePath)
+	}
+
+	// Extract the archive
+	color.Cyan("Extracting %s ...", archiveName)
+	if osName == "windows" {
+		err = unzip(ctx, archivePath, destDir)
+	} else {
+		err = untargz(ctx, archivePath, destDir)
+	}
+	if err != nil {
+		return fmt.Errorf("error extracting archive: %v", err)
+	}
+	return nil
+}
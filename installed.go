@@ -0,0 +1,479 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// installedGo is a Go toolchain found under an install root
+type installedGo struct {
+	Version string // From the tree's VERSION file, without the "go" prefix
+	Dir     string
+}
+
+// readGoVersionFile returns the version recorded in the VERSION file of a GOROOT
+func readGoVersionFile(goroot string) (string, error) {
+	f, err := os.Open(filepath.Join(goroot, "VERSION"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// The first line holds the version, later lines hold build metadata
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return "", fmt.Errorf("empty VERSION file in %s", goroot)
+	}
+	version := strings.TrimSpace(scanner.Text())
+	if !strings.HasPrefix(version, "go") {
+		return "", fmt.Errorf("unexpected VERSION file in %s: %q", goroot, version)
+	}
+	return strings.TrimPrefix(version, "go"), nil
+}
+
+// findInstalledGo returns the Go toolchains installed directly under installPath, newest first
+func findInstalledGo(installPath string) ([]installedGo, error) {
+	hostOS, hostArch := hostPlatform()
+	return findInstalledGoFor(installPath, hostOS, hostArch, false)
+}
+
+// findInstalledGoFor returns the Go toolchains for osName/arch installed directly under
+// installPath, newest first. Those of a foreign platform are named as by toolchainDirName.
+func findInstalledGoFor(installPath, osName, arch string, foreign bool) ([]installedGo, error) {
+	entries, err := os.ReadDir(installPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var installed []installedGo
+	for _, entry := range entries {
+		// Only real directories named like go<version> are toolchains; this skips symlinks
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "go") {
+			continue
+		}
+		dir := filepath.Join(installPath, entry.Name())
+		version, err := readGoVersionFile(dir)
+		if err != nil {
+			continue
+		}
+		if foreign {
+			if entry.Name() != toolchainDirName(version, osName, arch, true) {
+				continue
+			}
+		} else if rest := strings.TrimPrefix(entry.Name(), "go"+version); strings.HasPrefix(rest, ".") && strings.Contains(rest, "-") {
+			// Toolchains for other platforms, installed with --os or --arch, cannot run here
+			continue
+		}
+		installed = append(installed, installedGo{Version: version, Dir: dir})
+	}
+
+	slices.SortStableFunc(installed, func(a, b installedGo) int {
+		return compareGoVersions(b.Version, a.Version)
+	})
+	return installed, nil
+}
+
+// findInstalledVersion returns the toolchain under installPath that matches version exactly
+func findInstalledVersion(installPath, version string) (*installedGo, error) {
+	installed, err := findInstalledGo(installPath)
+	if err != nil {
+		return nil, err
+	}
+
+	version = strings.TrimPrefix(version, "go")
+	for i := range installed {
+		if compareGoVersions(installed[i].Version, version) == 0 || filepath.Base(installed[i].Dir) == "go"+version {
+			return &installed[i], nil
+		}
+	}
+	return nil, fmt.Errorf("Go %s is not installed in %s", version, installPath)
+}
+
+// resolveInstalled resolves a version spec against the toolchains installed under installPath.
+// It accepts the same specs as resolveVersion, except "oldstable".
+func resolveInstalled(installPath, spec string) (*installedGo, error) {
+	installed, err := findInstalledGo(installPath)
+	if err != nil {
+		return nil, err
+	}
+
+	spec = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(spec)), "go")
+
+	// newest returns the first (newest) installed toolchain matching the predicate
+	newest := func(match func(v string) bool) (*installedGo, error) {
+		for i := range installed {
+			if match(installed[i].Version) {
+				return &installed[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no installed Go toolchain in %s matches %q", installPath, spec)
+	}
+
+	switch spec {
+	case "", "-", "latest", "stable":
+		return newest(func(v string) bool { return !isPrerelease(v) })
+	}
+
+	if m := seriesSpec.FindStringSubmatch(spec); m != nil {
+		return newest(func(v string) bool { return goSeries(v) == "go"+m[1] && !isPrerelease(v) })
+	}
+
+	if m := prereleaseSpec.FindStringSubmatch(spec); m != nil {
+		return newest(func(v string) bool {
+			return (m[1] == "" || goSeries(v) == "go"+m[1]) && strings.Contains(v, m[2])
+		})
+	}
+
+	return findInstalledVersion(installPath, spec)
+}
+
+// activeGoroot returns the GOROOT of the toolchain active in the environment, or ""
+func activeGoroot() string {
+	if goroot := os.Getenv("GOROOT"); goroot != "" {
+		return goroot
+	}
+
+	// Fall back to the go binary found in PATH
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return ""
+	}
+	goBin, err = filepath.EvalSymlinks(goBin)
+	if err != nil {
+		return ""
+	}
+	return filepath.Dir(filepath.Dir(goBin))
+}
+
+// samePath reports whether two paths refer to the same location once symlinks are resolved
+func samePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(a); err == nil {
+		a = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(b); err == nil {
+		b = resolved
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// dirSize returns the total size of the regular files below dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// formatSize formats a byte count for humans
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// printListUsage prints the usage information for the list command
+func printListUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	fmt.Printf("%s: getgo list [install_path]\n", bold("Usage"))
+	fmt.Printf("\nLists the Go toolchains installed in install_path (default: current directory)\n")
+}
+
+// runList lists the Go toolchains installed under an install root
+func runList(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Usage = printListUsage
+	args = parseArgs(fs, args)
+
+	installPath := "."
+	switch len(args) {
+	case 0:
+	case 1:
+		installPath = args[0]
+	default:
+		printListUsage()
+		os.Exit(1)
+	}
+	installPath = expandPathOrExit(installPath)
+
+	installed, err := findInstalledGo(installPath)
+	if err != nil {
+		color.Red("Error reading %s: %v", installPath, err)
+		os.Exit(1)
+	}
+	if len(installed) == 0 {
+		color.Yellow("No Go toolchains installed in %s", installPath)
+		return
+	}
+
+	active := activeGoroot()
+	current := currentGoroot(installPath)
+	bold := color.New(color.Bold).SprintFunc()
+	fmt.Printf("%s\n", bold(fmt.Sprintf("  %-14s %-10s %s", "VERSION", "SIZE", "PATH")))
+
+	for _, goInst := range installed {
+		size := "?"
+		if n, err := dirSize(goInst.Dir); err == nil {
+			size = formatSize(n)
+		}
+
+		var notes []string
+		if samePath(goInst.Dir, active) {
+			notes = append(notes, "active")
+		}
+		if samePath(goInst.Dir, current) {
+			notes = append(notes, "default")
+		}
+
+		line := fmt.Sprintf("%-14s %-10s %s", goInst.Version, size, goInst.Dir)
+		if len(notes) > 0 {
+			line += " (" + strings.Join(notes, ", ") + ")"
+		}
+		if samePath(goInst.Dir, active) {
+			color.Green("* %s", line)
+		} else {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+}
+
+// printUninstallUsage prints the usage information for the uninstall command
+func printUninstallUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Printf("%s: getgo uninstall [options] <version> [install_path]\n", bold("Usage"))
+	fmt.Printf("%s:\n", bold("Examples"))
+	fmt.Printf("  %s        # Remove ./go1.22.3\n", cyan("getgo uninstall 1.22.3"))
+	fmt.Printf("  %s  # Remove ~/.go/go1.22.3\n", cyan("getgo uninstall 1.22.3 ~/.go"))
+
+	fmt.Printf("\n%s:\n", bold("Options"))
+	fmt.Printf("  -f, --force        Remove the toolchain even if GOROOT points to it\n")
+	fmt.Printf("  -y, --yes          Do not ask for confirmation\n")
+	fmt.Printf("  --envrc PATH       Also offer to clean up the .envrc file at PATH (default: ./.envrc)\n")
+}
+
+// runUninstall removes an installed Go toolchain
+func runUninstall(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	fs.Usage = printUninstallUsage
+	forceFlag := fs.Bool("force", false, "Remove the toolchain even if GOROOT points to it")
+	fFlag := fs.Bool("f", false, "Remove the toolchain even if GOROOT points to it (shorthand)")
+	yesFlag := fs.Bool("yes", false, "Do not ask for confirmation")
+	yFlag := fs.Bool("y", false, "Do not ask for confirmation (shorthand)")
+	envrcFlag := fs.String("envrc", ".envrc", "Path of a .envrc file to clean up")
+	args = parseArgs(fs, args)
+
+	installPath := "."
+	switch len(args) {
+	case 1:
+	case 2:
+		installPath = args[1]
+	default:
+		printUninstallUsage()
+		os.Exit(1)
+	}
+	installPath = expandPathOrExit(installPath)
+	assumeYes := *yesFlag || *yFlag
+
+	goInst, err := findInstalledVersion(installPath, args[0])
+	if err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if goroot := os.Getenv("GOROOT"); samePath(goInst.Dir, goroot) && !(*forceFlag || *fFlag) {
+		color.Red("Refusing to remove Go %s: GOROOT points to %s", goInst.Version, goroot)
+		fmt.Println("Use --force to remove it anyway")
+		os.Exit(1)
+	}
+
+	isDefault := samePath(goInst.Dir, currentGoroot(installPath))
+	if isDefault && !(*forceFlag || *fFlag) {
+		color.Red("Refusing to remove Go %s: it is the default version (%s)", goInst.Version, currentLinkPath(installPath))
+		fmt.Println("Switch to another version with 'getgo use' or use --force to remove it anyway")
+		os.Exit(1)
+	}
+
+	if !assumeYes && !confirm(ctx, fmt.Sprintf("Remove Go %s at %s?", goInst.Version, goInst.Dir)) {
+		color.Yellow("Aborted")
+		return
+	}
+
+	if err := os.RemoveAll(goInst.Dir); err != nil {
+		color.Red("Error removing %s: %v", goInst.Dir, err)
+		os.Exit(1)
+	}
+	color.Green("Go %s has been removed from %s", goInst.Version, goInst.Dir)
+
+	// Do not leave a dangling current symlink behind
+	linkRemoved := isDefault && !replaceDefault(installPath)
+
+	// Offer to clean up configuration that still references the removed tree, or the link
+	removed := []string{goInst.Dir}
+	if linkRemoved {
+		removed = append(removed, currentLinkPath(installPath))
+	}
+	configFiles := []string{}
+	if rcFile := getShellConfigFile(); rcFile != "" {
+		configFiles = append(configFiles, rcFile)
+	}
+	if envrc, err := expandPath(*envrcFlag); err == nil {
+		if info, err := os.Stat(envrc); err == nil && info.IsDir() {
+			envrc = filepath.Join(envrc, ".envrc")
+		}
+		configFiles = append(configFiles, envrc)
+	}
+
+	for _, file := range configFiles {
+		if err := removeReferencingConfig(ctx, file, assumeYes, removed...); err != nil {
+			color.Red("Error cleaning up %s: %v", file, err)
+		}
+	}
+}
+
+// replaceDefault points the current symlink of installPath at the newest remaining toolchain
+// after the default one was removed, or removes the link if there is none. It reports whether
+// the link still exists.
+func replaceDefault(installPath string) bool {
+	link := currentLinkPath(installPath)
+	installed, err := findInstalledGo(installPath)
+	if err == nil && len(installed) > 0 {
+		gopath, err := defaultGopath()
+		if err == nil {
+			err = setCurrentGo(installPath, installed[0].Dir, gopath)
+		}
+		if err == nil {
+			color.Green("%s now points to Go %s, the newest remaining version", link, installed[0].Version)
+			return true
+		}
+		color.Yellow("Could not point %s at Go %s: %v", link, installed[0].Version, err)
+	}
+
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		color.Red("Error removing %s: %v", link, err)
+		return true
+	}
+	color.Yellow("Removed %s, as no other Go version is left in %s to make the default", link, installPath)
+	return false
+}
+
+// referencesPath reports whether line mentions path as a whole path, not as a prefix of a longer name
+func referencesPath(line, path string) bool {
+	for rest := line; ; {
+		i := strings.Index(rest, path)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(path):]
+		if rest == "" || strings.ContainsAny(rest[:1], "/\\\"': \t\r\n;") {
+			return true
+		}
+	}
+}
+
+// removeReferencingConfig offers to clean up a shell configuration or .envrc file that references
+// any of paths. A getgo block that does is removed as a whole, as are other lines that do. The
+// file is rewritten like updateRCFile does, keeping a backup.
+func removeReferencingConfig(ctx context.Context, file string, assumeYes bool, paths ...string) error {
+	// Edit the target of a symlink, as dotfile managers link configuration files
+	if target, err := filepath.EvalSymlinks(file); err == nil {
+		file = target
+	}
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	references := func(line string) bool {
+		return slices.ContainsFunc(paths, func(path string) bool { return referencesPath(line, path) })
+	}
+
+	lines := splitLines(string(content))
+	start, end, found, err := findRCBlock(lines)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	if !found {
+		start, end, found = findLegacyRCBlock(lines)
+	}
+	removeBlock := found && slices.ContainsFunc(lines[start:end+1], references)
+
+	var kept, removed []string
+	for i, line := range lines {
+		if !(found && i >= start && i <= end) && references(line) {
+			removed = append(removed, line)
+		} else {
+			kept = append(kept, line)
+		}
+	}
+	if !removeBlock && len(removed) == 0 {
+		return nil
+	}
+
+	if removeBlock {
+		color.Yellow("The getgo block in %s references the removed toolchain", file)
+	}
+	if len(removed) > 0 {
+		if removeBlock {
+			color.Yellow("So do these lines outside the block:")
+		} else {
+			color.Yellow("%s references the removed toolchain:", file)
+		}
+		for _, line := range removed {
+			fmt.Printf("  %s\n", strings.TrimRight(line, "\r"))
+		}
+	}
+	if !assumeYes && !confirm(ctx, fmt.Sprintf("Remove them from %s?", file)) {
+		return nil
+	}
+
+	newContent := joinLines(kept)
+	if removeBlock {
+		if newContent, err = replaceRCBlock(newContent, ""); err != nil {
+			return err
+		}
+	}
+	if err := writeRCFile(file, string(content), newContent); err != nil {
+		return err
+	}
+	if removeBlock {
+		color.Green("Removed the getgo block from %s", file)
+	}
+	if len(removed) > 0 {
+		color.Green("Removed %d line(s) from %s", len(removed), file)
+	}
+	return nil
+}
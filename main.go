@@ -1,102 +1,39 @@
 package main
 
 import (
-	"archive/tar"
-	"archive/zip"
-	"compress/gzip"
-	"encoding/json"
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"os/user"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"time"
+	"syscall"
 
 	"github.com/fatih/color"
 )
 
-type GoVersion struct {
-	Version string `json:"version"`
-	Stable  bool   `json:"stable"`
-}
-
-// progressReader is a custom io.Reader that tracks download progress
-type progressReader struct {
-	reader         io.Reader
-	totalBytes     int64
-	readBytes      int64
-	lastPercentage int
-	lastUpdateTime time.Time
-}
-
-func newProgressReader(reader io.Reader, totalBytes int64) *progressReader {
-	return &progressReader{
-		reader:         reader,
-		totalBytes:     totalBytes,
-		lastUpdateTime: time.Now(),
-	}
-}
-
-func (pr *progressReader) Read(p []byte) (int, error) {
-	n, err := pr.reader.Read(p)
-	pr.readBytes += int64(n)
-
-	// Update progress every 100ms to avoid too many updates
-	if time.Since(pr.lastUpdateTime) > 100*time.Millisecond {
-		percentage := int(float64(pr.readBytes) / float64(pr.totalBytes) * 100)
-
-		// Only update if percentage changed
-		if percentage != pr.lastPercentage && percentage <= 100 {
-			pr.lastPercentage = percentage
-			pr.lastUpdateTime = time.Now()
-
-			// Create progress bar (Windows-compatible approach)
-			progressBar := renderProgressBar(percentage)
-
-			// Print the progress bar
-			fmt.Print(progressBar)
-		}
-	}
-
-	return n, err
-}
-
-// renderProgressBar creates a progress bar string that works on all platforms
-func renderProgressBar(percentage int) string {
-	// Ensure percentage is within bounds
-	if percentage < 0 {
-		percentage = 0
-	} else if percentage > 100 {
-		percentage = 100
-	}
-
-	// Calculate the width of the progress bar (50 characters)
-	width := 50
-	completed := width * percentage / 100
-
-	// Build the progress bar
-	var sb strings.Builder
-
-	// Use carriage return to return to beginning of line
-	sb.WriteString("\r")
-
-	// Write the progress bar
-	sb.WriteString("Downloading: [")
-	sb.WriteString(strings.Repeat("=", completed))
-	if completed < width {
-		sb.WriteString(strings.Repeat(" ", width-completed))
-	}
-	sb.WriteString("] ")
-
-	// Write the percentage
-	sb.WriteString(fmt.Sprintf("%3d%%", percentage))
-
-	return sb.String()
+// exitCanceled is the exit status after SIGINT or SIGTERM, the one shells report for SIGINT
+const exitCanceled = 130
+
+// commands maps subcommand names to their entry points
+var commands = map[string]func(ctx context.Context, args []string){
+	"cache":       runCache,
+	"env":         runEnv,
+	"envrc":       runEnvrc,
+	"hook":        runHook,
+	"hook-env":    runHookEnv,
+	"install":     runInstall,
+	"list":        runList,
+	"list-remote": runListRemote,
+	"shim":        runShimCommand,
+	"sync":        runSync,
+	"uninstall":   runUninstall,
+	"use":         runUse,
 }
 
 // printUsage prints the usage information for the getgo command
@@ -110,29 +47,111 @@ func printUsage() {
 	fmt.Printf("  %s                # Latest version in current directory\n", cyan("getgo -"))
 	fmt.Printf("  %s           # Latest version in current directory\n", cyan("getgo latest"))
 	fmt.Printf("  %s           # Specific version in current directory\n", cyan("getgo 1.23.1"))
+	fmt.Printf("  %s             # Newest patch release of Go 1.22\n", cyan("getgo 1.22"))
+	fmt.Printf("  %s        # Newest release of the previous minor version\n", cyan("getgo oldstable"))
+	fmt.Printf("  %s           # Newest release candidate of Go 1.23\n", cyan("getgo 1.23rc"))
 	fmt.Printf("  %s     # Latest version in ~/.go\n", cyan("getgo latest ~/.go"))
 	fmt.Printf("  %s  # Specific version in /usr/local/go\n", cyan("getgo 1.23.1 /usr/local/go"))
 	fmt.Printf("  %s # Custom GOPATH\n", cyan("getgo --path ~/custom/gopath"))
+	fmt.Printf("  %s # Install a downloaded archive into ~/.go\n", cyan("getgo install --from ./go1.22.3.linux-amd64.tar.gz ~/.go"))
+	fmt.Printf("  %s # Windows toolchain in ./go1.22.3.windows-amd64\n", cyan("getgo --os windows --arch amd64 1.22.3"))
+
+	fmt.Printf("\n%s:\n", bold("Commands"))
+	fmt.Printf("  cache              List, prune or clear the download cache\n")
+	fmt.Printf("  env                Print the environment of a toolchain, or manage the getgo block in shell files\n")
+	fmt.Printf("  envrc              Report stale .envrc files, or install the 'use getgo' direnv library\n")
+	fmt.Printf("  hook               Print a bash, zsh or fish hook that switches Go versions on cd\n")
+	fmt.Printf("  install            Install a Go version (same as getgo without a command)\n")
+	fmt.Printf("  list               List installed Go toolchains\n")
+	fmt.Printf("  list-remote        List Go releases available for download\n")
+	fmt.Printf("  shim               Install go/gofmt shims that pick the version from go.mod, go.work or .go-version\n")
+	fmt.Printf("  sync               Install the Go version a project requires and update its .envrc\n")
+	fmt.Printf("  uninstall          Remove an installed Go toolchain\n")
+	fmt.Printf("  use                Switch the default Go version (install_path/go symlink)\n")
 
 	fmt.Printf("\n%s:\n", bold("Options"))
 	fmt.Printf("  -h, --help         Show this help message\n")
 	fmt.Printf("  -u, --unattended   Automatically set up environment variables (default: disabled)\n")
 	fmt.Printf("  -p, --path PATH    Set custom GOPATH (default is $HOME/go)\n")
-	fmt.Printf("  --envrc PATH       Create a .envrc file with Go environment variables at the specified path\n")
+	fmt.Printf("  --envrc PATH       Create or update the getgo block of a .envrc file at the specified path\n")
+	fmt.Printf("  --no-verify        Skip checksum verification (release manifest or checksum database)\n")
+	fmt.Printf("  --from FILE|URL    Install a release archive from a local file or URL, reading the version from it\n")
+	fmt.Printf("  --sha256 HEX       Expected SHA-256 of the --from archive (default: the release manifest entry)\n")
+	fmt.Printf("  --stream           Extract .tar.gz archives while downloading, without saving them first\n")
+	fmt.Printf("  -f, --force        Reinstall the version if it is already installed\n")
+	fmt.Printf("  --os OS            Install for another operating system, into go<version>.<os>-<arch>\n")
+	fmt.Printf("  --arch ARCH        Install for another architecture, into go<version>.<os>-<arch>\n")
+	fmt.Printf("  --default          Make this version the default (install_path/go symlink)\n")
+	fmt.Printf("  --offline          Install from the cached manifest and archives only (or GETGO_OFFLINE=1)\n")
+	fmt.Printf("  --mirror URL       Download mirror to try before falling back to the next (or GETGO_MIRRORS)\n")
+	fmt.Printf("  --source SOURCE    Download from dl (go.dev or mirrors) or proxy (GOPROXY) (or GETGO_SOURCE)\n")
 }
 
 func main() {
-	// Define flags
-	helpFlag := flag.Bool("help", false, "Show usage information")
-	hFlag := flag.Bool("h", false, "Show usage information")
-	unattendedFlag := flag.Bool("unattended", false, "Automatically set up environment variables")
-	uFlag := flag.Bool("u", false, "Automatically set up environment variables (shorthand)")
-	gopathFlag := flag.String("path", "", "Custom GOPATH (default is $HOME/go)")
-	gopathShortFlag := flag.String("p", "", "Custom GOPATH (shorthand)")
-	envrcFlag := flag.String("envrc", "", "Path to add .envrc file with Go environment variables")
+	// SIGINT and SIGTERM cancel ctx so that work in progress can clean up; a second signal
+	// kills the process right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Invoked through a go or gofmt shim
+	if name := shimName(os.Args[0]); name != "" {
+		runShim(ctx, name, os.Args[1:])
+		return
+	}
+
+	// Dispatch subcommands, which parse their own flags
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			run(ctx, os.Args[2:])
+			return
+		}
+	}
 
-	flag.Parse()
-	args := flag.Args()
+	runInstall(ctx, os.Args[1:])
+}
+
+// exitIfCanceled exits with exitCanceled if ctx was canceled by a signal. By then the
+// interrupted work has returned, running its cleanup.
+func exitIfCanceled(ctx context.Context) {
+	if ctx.Err() == nil {
+		return
+	}
+	fmt.Fprintln(color.Output) // End a progress bar the signal interrupted
+	color.Yellow("Interrupted")
+	os.Exit(exitCanceled)
+}
+
+// runInstall installs a Go version; it is the root command and the install subcommand
+func runInstall(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("getgo", flag.ExitOnError)
+	fs.Usage = printUsage
+
+	// Define flags
+	helpFlag := fs.Bool("help", false, "Show usage information")
+	hFlag := fs.Bool("h", false, "Show usage information")
+	unattendedFlag := fs.Bool("unattended", false, "Automatically set up environment variables")
+	uFlag := fs.Bool("u", false, "Automatically set up environment variables (shorthand)")
+	gopathFlag := fs.String("path", "", "Custom GOPATH (default is $HOME/go)")
+	gopathShortFlag := fs.String("p", "", "Custom GOPATH (shorthand)")
+	envrcFlag := fs.String("envrc", "", "Path to add .envrc file with Go environment variables")
+	noVerifyFlag := fs.Bool("no-verify", false, "Skip checksum verification of the downloaded archive")
+	forceFlag := fs.Bool("force", false, "Reinstall the version if it is already installed")
+	fFlag := fs.Bool("f", false, "Reinstall the version if it is already installed (shorthand)")
+	osFlag := fs.String("os", "", "Install for another operating system")
+	archFlag := fs.String("arch", "", "Install for another architecture")
+	defaultFlag := fs.Bool("default", false, "Point the current symlink at the installed version")
+	fromFlag := fs.String("from", "", "Install a release archive from a local file or URL")
+	sha256Flag := fs.String("sha256", "", "Expected SHA-256 of the --from archive")
+	streamFlag := fs.Bool("stream", false, "Extract .tar.gz archives while downloading")
+	fs.BoolVar(&offline, "offline", offline, "Install from the cached manifest and archives only")
+	fs.Var(&mirrorFlag, "mirror", "Download mirror base URL (repeatable, comma-separated)")
+	fs.StringVar(&sourceFlag, "source", "", "Download from dl (go.dev or mirrors) or proxy (GOPROXY)")
+
+	args = parseArgs(fs, args)
 
 	// Check if help was requested
 	if isHelpRequested(helpFlag, hFlag) {
@@ -145,12 +164,21 @@ func main() {
 	installPath := "." // Default to current directory
 
 	// Parse arguments based on how many are provided
-	switch len(args) {
-	case 0:
+	switch {
+	case *fromFlag != "":
+		// The version comes from the archive, so only the install path may be given
+		if len(args) > 1 {
+			color.Red("Error: --from reads the version from the archive; only install_path may be given")
+			os.Exit(1)
+		}
+		if len(args) == 1 {
+			installPath = args[0]
+		}
+	case len(args) == 0:
 		// Use defaults (latest version, current directory)
-	case 1:
+	case len(args) == 1:
 		versionArg = args[0]
-	case 2:
+	case len(args) == 2:
 		versionArg = args[0]
 		installPath = args[1]
 	default:
@@ -161,38 +189,6 @@ func main() {
 	// Expand and convert installPath to absolute path
 	installPath = expandPathOrExit(installPath)
 
-	// Get the version to download
-	version := versionArg
-	if version == "latest" || version == "-" {
-		var err error
-		color.Cyan("Fetching latest Go version...")
-		version, err = getLatestGoVersion()
-		if err != nil {
-			color.Red("Error getting latest Go version: %v", err)
-			os.Exit(1)
-		}
-		color.Green("Latest Go version is %s", version)
-	}
-
-	// Create the download URL
-	osName := runtime.GOOS
-	arch := runtime.GOARCH
-
-	var archiveExt string
-	if osName == "windows" {
-		archiveExt = "zip"
-	} else {
-		archiveExt = "tar.gz"
-	}
-
-	downloadURL := fmt.Sprintf("https://go.dev/dl/go%s.%s-%s.%s", version, osName, arch, archiveExt)
-
-	// Check if the version already exists at the destination
-	versionedGoDir := filepath.Join(installPath, fmt.Sprintf("go%s", version))
-
-	// Ensure versionedGoDir is absolute (should already be since installPath is absolute)
-	versionedGoDir = expandPathOrExit(versionedGoDir)
-
 	// Get the user's home directory for GOPATH
 	usr, err := user.Current()
 	if err != nil {
@@ -214,108 +210,65 @@ func main() {
 		//color.Cyan("Using custom GOPATH: %s", gopath)
 	}
 
-	if _, err := os.Stat(versionedGoDir); err == nil {
-		color.Yellow("Go version %s already exists at %s", version, versionedGoDir)
-
-		// Print environment variables
-		printEnvVars(versionedGoDir, gopath)
-
-		// Set up environment variables if requested
-		if isUnattendedMode(unattendedFlag, uFlag) {
-			setupEnvironmentVariables(versionedGoDir, gopath)
-		}
-
-		// Set up .envrc file if requested
-		setupEnvrcIfRequested(envrcFlag, versionedGoDir, gopath)
-
-		os.Exit(0)
-	}
-
-	// Create the installation directory if it doesn't exist
-	err = os.MkdirAll(installPath, 0755)
-	if err != nil {
-		color.Red("Error creating installation directory: %v", err)
-		os.Exit(1)
-	}
-
-	// Download the Go archive
-	color.Cyan("Downloading Go %s for %s/%s...", version, osName, arch)
-	archivePath := filepath.Join(os.TempDir(), fmt.Sprintf("go%s.%s-%s.%s", version, osName, arch, archiveExt))
-	err = downloadFileWithProgress(downloadURL, archivePath)
-	if err != nil {
-		if strings.Contains(err.Error(), "404") {
-			color.Red("Error: Go version %s not found for %s/%s", version, osName, arch)
-			fmt.Println("Please check that the version exists at https://go.dev/dl/")
-		} else {
-			color.Red("Error downloading Go archive: %v", err)
-		}
-		os.Exit(1)
-	}
-	fmt.Println() // Add a newline after progress bar
-
-	// Extract the archive
-	color.Cyan("Extracting to %s ...", installPath)
-
-	// Create a temporary directory for extraction
-	tempDir, err := os.MkdirTemp("", "getgo-extract")
+	// Resolve, download and extract the requested version
+	opts := installOptions{
+		versionSpec: versionArg,
+		installPath: installPath,
+		noVerify:    *noVerifyFlag,
+		fromArchive: *fromFlag,
+		sha256:      *sha256Flag,
+		stream:      *streamFlag,
+		force:       *forceFlag || *fFlag,
+		osName:      *osFlag,
+		arch:        *archFlag,
+	}
+	versionedGoDir, err := installGo(ctx, opts)
+	exitIfCanceled(ctx)
 	if err != nil {
-		color.Red("Error creating temporary directory: %v", err)
+		color.Red("Error: %v", err)
 		os.Exit(1)
 	}
-	defer os.RemoveAll(tempDir)
 
-	if osName == "windows" {
-		err = unzip(archivePath, tempDir)
-	} else {
-		err = untargz(archivePath, tempDir)
-	}
-	if err != nil {
-		color.Red("Error extracting archive: %v", err)
-		os.Exit(1)
+	// A toolchain for another platform is only unpacked; it cannot run here
+	if osName, arch, foreign := opts.platform(); foreign {
+		color.Yellow("Skipping environment setup for a %s/%s toolchain", osName, arch)
+		return
 	}
 
-	// Move the extracted "go" directory to the versioned directory
-	extractedGoDir := filepath.Join(tempDir, "go")
-	versionedGoDir = filepath.Join(installPath, fmt.Sprintf("go%s", version))
-
-	// Ensure versionedGoDir is absolute (should already be since installPath is absolute)
-	versionedGoDir = expandPathOrExit(versionedGoDir)
+	// Print and set up the environment for the installation
+	activateInstall(installPath, versionedGoDir, gopath, *defaultFlag, isUnattendedMode(unattendedFlag, uFlag), envrcFlag)
+}
 
-	// Remove the destination directory if it already exists
-	if _, err := os.Stat(versionedGoDir); err == nil {
-		if err := os.RemoveAll(versionedGoDir); err != nil {
-			color.Red("Error removing existing directory: %v", err)
-			os.Exit(1)
+// activateInstall updates the current symlink and sets up the environment for an installed toolchain.
+// The shell environment points at the current symlink when it targets goroot, so later version
+// switches only need to move the link; .envrc files keep pinning the versioned directory.
+func activateInstall(installPath, goroot, gopath string, makeDefault, unattended bool, envrcFlag *string) {
+	// The first toolchain in an install root becomes the default automatically, unless the
+	// link would end up in GOPATH, as for installs into the home directory
+	autoLink := currentGoroot(installPath) == "" && checkCurrentLink(installPath, gopath) == nil
+	if makeDefault || autoLink {
+		if err := setCurrentGo(installPath, goroot, gopath); err != nil {
+			color.Yellow("Could not update the current symlink: %v", err)
+		} else {
+			color.Green("%s now points to %s", currentLinkPath(installPath), filepath.Base(goroot))
 		}
 	}
 
-	// Create the parent directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(versionedGoDir), 0755); err != nil {
-		color.Red("Error creating parent directory: %v", err)
-		os.Exit(1)
+	envGoroot := goroot
+	if samePath(currentGoroot(installPath), goroot) {
+		envGoroot = currentLinkPath(installPath)
 	}
 
-	// Rename the extracted directory to the versioned directory
-	if err := os.Rename(extractedGoDir, versionedGoDir); err != nil {
-		color.Red("Error moving extracted directory: %v", err)
-		os.Exit(1)
-	}
-
-	// Clean up the downloaded archive
-	os.Remove(archivePath)
-
-	color.Green("Go %s has been successfully installed to %s", version, versionedGoDir)
-
 	// Print environment variables
-	printEnvVars(versionedGoDir, gopath)
+	printEnvVars(envGoroot, gopath)
 
 	// Set up environment variables if requested
-	if isUnattendedMode(unattendedFlag, uFlag) {
-		setupEnvironmentVariables(versionedGoDir, gopath)
+	if unattended {
+		setupEnvironmentVariables(envGoroot, gopath)
 	}
 
 	// Set up .envrc file if requested
-	setupEnvrcIfRequested(envrcFlag, versionedGoDir, gopath)
+	setupEnvrcIfRequested(envrcFlag, goroot, gopath)
 }
 
 // setupEnvironmentVariables sets up environment variables in the appropriate configuration files
@@ -330,67 +283,43 @@ func setupEnvironmentVariables(goroot, gopath string) {
 // setupUnixEnvironment sets up environment variables in Unix-like systems (Linux, macOS)
 func setupUnixEnvironment(goroot, gopath string) {
 	// Determine the shell configuration file
+	sh := detectShell()
 	shellConfigFile := getShellConfigFile()
 	if shellConfigFile == "" {
 		color.Yellow("Could not determine shell configuration file. Please set up environment variables manually.")
 		return
 	}
 
-	// Create environment variable exports
-	exports := []string{
-		fmt.Sprintf("export GOROOT=%s", goroot),
-		fmt.Sprintf("export GOPATH=%s", gopath),
-		fmt.Sprintf("export PATH=$PATH:$GOPATH/bin:$GOROOT/bin"),
-	}
-
-	// Check if the file exists
-	if _, err := os.Stat(shellConfigFile); os.IsNotExist(err) {
-		color.Yellow("Shell configuration file %s does not exist. Creating it...", shellConfigFile)
-		os.Create(shellConfigFile)
-	}
-
-	// Read the current content of the file
-	content, err := os.ReadFile(shellConfigFile)
+	// Write the variables into the block getgo manages, replacing what an earlier install wrote
+	changed, err := updateRCFile(shellConfigFile, rcBlock(sh.goEnv(goroot, gopath)), false)
 	if err != nil {
-		color.Red("Error reading shell configuration file: %v", err)
+		color.Red("Error updating shell configuration file: %v", err)
 		return
 	}
-
-	// Check if Go environment variables are already set
-	if strings.Contains(string(content), "GOROOT=") {
-		color.Yellow("Go environment variables already exist in %s", shellConfigFile)
-		color.Yellow("You may need to update them manually:")
-		for _, export := range exports {
-			fmt.Println(export)
-		}
+	if !changed {
+		color.Green("Go environment variables in %s already point to %s", shellConfigFile, goroot)
 		return
 	}
 
-	// Append the exports to the file
-	f, err := os.OpenFile(shellConfigFile, os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		color.Red("Error opening shell configuration file: %v", err)
-		return
+	color.Green("Go environment variables in %s now point to %s", shellConfigFile, goroot)
+	warnRCAssignments(shellConfigFile)
+	if source := sh.sourceCommand(shellConfigFile); source != "" {
+		color.Yellow("Run '%s' to apply the changes to your current shell", source)
+	} else {
+		color.Yellow("Restart your shell to apply the changes")
 	}
-	defer f.Close()
+}
 
-	// Write a comment and the exports
-	_, err = f.WriteString("\n# Go environment variables added by getgo\n")
+// warnRCAssignments warns about lines outside the managed block of a shell configuration file
+// that set GOROOT or GOPATH or add a Go bin directory to PATH, which getgo leaves alone
+func warnRCAssignments(path string) {
+	content, err := os.ReadFile(path)
 	if err != nil {
-		color.Red("Error writing to shell configuration file: %v", err)
 		return
 	}
-
-	for _, export := range exports {
-		_, err = f.WriteString(export + "\n")
-		if err != nil {
-			color.Red("Error writing to shell configuration file: %v", err)
-			return
-		}
+	for _, n := range rcLinesOutsideBlock(string(content), setsGoEnv) {
+		color.Yellow("%s:%d also sets GOROOT, GOPATH or a Go bin directory in PATH outside the getgo block; remove it if it conflicts", path, n)
 	}
-
-	color.Green("Go environment variables have been added to %s", shellConfigFile)
-	color.Yellow("Run 'source %s' to apply the changes to your current shell", shellConfigFile)
 }
 
 // setupWindowsEnvironment sets up environment variables in Windows
@@ -436,254 +365,28 @@ func setupWindowsEnvironment(goroot, gopath string) {
 	color.Yellow("Please restart your terminal or system for the changes to take effect")
 }
 
-// getShellConfigFile determines the appropriate shell configuration file
+// getShellConfigFile determines the configuration file of the shell getgo was started from
 func getShellConfigFile() string {
-	// Get the current shell
-	shell := os.Getenv("SHELL")
-
 	// Get the user's home directory
 	usr, err := user.Current()
 	if err != nil {
 		return ""
 	}
-
-	// Determine the configuration file based on the shell
-	switch {
-	case strings.Contains(shell, "zsh"):
-		return filepath.Join(usr.HomeDir, ".zshrc")
-	case strings.Contains(shell, "bash"):
-		// Check for .bash_profile first on macOS
-		if runtime.GOOS == "darwin" {
-			bashProfile := filepath.Join(usr.HomeDir, ".bash_profile")
-			if _, err := os.Stat(bashProfile); err == nil {
-				return bashProfile
-			}
-		}
-		return filepath.Join(usr.HomeDir, ".bashrc")
-	case strings.Contains(shell, "fish"):
-		fishConfig := filepath.Join(usr.HomeDir, ".config", "fish", "config.fish")
-		// Create the directory if it doesn't exist
-		os.MkdirAll(filepath.Dir(fishConfig), 0755)
-		return fishConfig
-	default:
-		// Try to find a common shell configuration file
-		for _, file := range []string{".profile", ".bashrc", ".bash_profile", ".zshrc"} {
-			path := filepath.Join(usr.HomeDir, file)
-			if _, err := os.Stat(path); err == nil {
-				return path
-			}
-		}
-	}
-
-	// Default to .profile if no other file is found
-	return filepath.Join(usr.HomeDir, ".profile")
+	return detectShell().configFile(usr.HomeDir)
 }
 
-// printEnvVars prints the environment variables needed for Go based on the OS
+// printEnvVars prints the environment variables needed for Go in the syntax of the current shell
 func printEnvVars(goroot, gopath string) {
 	bold := color.New(color.Bold).SprintFunc()
+	sh := detectShell()
 
-	if runtime.GOOS == "windows" {
-		fmt.Printf("\n%s:\n\n", bold("Go environment variables"))
-		fmt.Printf("GOROOT=%s\n", goroot)
-		fmt.Printf("GOPATH=%s\n", gopath)
-		fmt.Printf("PATH=%%PATH%%;%%GOPATH%%\\bin;%%GOROOT%%\\bin\n")
-	} else {
-		fmt.Printf("\n%s:\n\n", bold("Go environment variables"))
-		fmt.Printf("export GOROOT=%s\n", goroot)
-		fmt.Printf("export GOPATH=%s\n", gopath)
-		fmt.Printf("export PATH=$PATH:$GOPATH/bin:$GOROOT/bin\n")
+	fmt.Printf("\n%s (%s):\n\n", bold("Go environment variables"), sh)
+	for _, line := range sh.goEnv(goroot, gopath) {
+		fmt.Println(line)
 	}
 	fmt.Println()
 }
 
-func getLatestGoVersion() (string, error) {
-	resp, err := http.Get("https://go.dev/dl/?mode=json")
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var versions []GoVersion
-	err = json.NewDecoder(resp.Body).Decode(&versions)
-	if err != nil {
-		return "", err
-	}
-
-	if len(versions) == 0 {
-		return "", fmt.Errorf("no Go versions found")
-	}
-
-	// Find the first stable version
-	for _, v := range versions {
-		if v.Stable {
-			// Remove the "go" prefix from the version
-			return strings.TrimPrefix(v.Version, "go"), nil
-		}
-	}
-
-	// If no stable version is found, return the first version
-	return strings.TrimPrefix(versions[0].Version, "go"), nil
-}
-
-func downloadFileWithProgress(url, filepath string) error {
-	// Send HEAD request to get the file size
-	headResp, err := http.Head(url)
-	if err != nil {
-		return err
-	}
-	defer headResp.Body.Close()
-
-	if headResp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s (URL: %s)", headResp.Status, url)
-	}
-
-	totalBytes := headResp.ContentLength
-
-	// Now send the actual GET request
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s (URL: %s)", resp.Status, url)
-	}
-
-	out, err := os.Create(filepath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	// Create a progress reader
-	progressR := newProgressReader(resp.Body, totalBytes)
-
-	// Copy the data using the progress reader
-	_, err = io.Copy(out, progressR)
-
-	// Ensure the progress bar shows 100% when download is complete
-	fmt.Print(renderProgressBar(100))
-
-	return err
-}
-
-func downloadFile(url, filepath string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s (URL: %s)", resp.Status, url)
-	}
-
-	out, err := os.Create(filepath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
-}
-
-func untargz(src, dst string) error {
-	file, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	gzr, err := gzip.NewReader(file)
-	if err != nil {
-		return err
-	}
-	defer gzr.Close()
-
-	tr := tar.NewReader(gzr)
-
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		path := filepath.Join(dst, header.Name)
-
-		switch header.Typeflag {
-		case tar.TypeDir:
-			if err := os.MkdirAll(path, 0755); err != nil {
-				return err
-			}
-		case tar.TypeReg:
-			dir := filepath.Dir(path)
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return err
-			}
-			outFile, err := os.Create(path)
-			if err != nil {
-				return err
-			}
-			if _, err := io.Copy(outFile, tr); err != nil {
-				outFile.Close()
-				return err
-			}
-			outFile.Close()
-			if err := os.Chmod(path, os.FileMode(header.Mode)); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
-func unzip(src, dst string) error {
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return err
-	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		path := filepath.Join(dst, f.Name)
-
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(path, f.Mode())
-			continue
-		}
-
-		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-			return err
-		}
-
-		outFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return err
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			outFile.Close()
-			return err
-		}
-
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
-
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 // expandPath expands a path with ~ and converts it to an absolute path
 func expandPath(path string) (string, error) {
 	// Expand ~ in the path
@@ -712,6 +415,45 @@ func expandPath(path string) (string, error) {
 	return path, nil
 }
 
+// parseArgs parses flags appearing anywhere in args and returns the remaining positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		// The flag set exits on error, so Parse never returns one here
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// stdinReader reads the answers to confirm; it is shared so that no answer is lost in the
+// buffer of an earlier question
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes/no question on stdin and reports whether the answer was yes.
+// The signal handler keeps an interrupt from ending a blocked read, so the wait ends with ctx.
+func confirm(ctx context.Context, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answers := make(chan string, 1)
+	go func() {
+		answer, _ := stdinReader.ReadString('\n')
+		answers <- answer
+	}()
+
+	var answer string
+	select {
+	case answer = <-answers:
+	case <-ctx.Done():
+		exitIfCanceled(ctx)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 // isUnattendedMode checks if unattended mode is enabled
 func isUnattendedMode(unattendedFlag, uFlag *bool) bool {
 	return *unattendedFlag || *uFlag
@@ -753,7 +495,7 @@ func setupEnvrcIfRequested(envrcFlag *string, goroot, gopath string) {
 	}
 }
 
-// setupEnvrcFile creates or updates a .envrc file with Go environment variables
+// setupEnvrcFile creates or updates the getgo block of a .envrc file with Go environment variables
 func setupEnvrcFile(envrcPath, goroot, gopath string) error {
 	// Expand the path if needed
 	expandedPath, err := expandPath(envrcPath)
@@ -766,105 +508,22 @@ func setupEnvrcFile(envrcPath, goroot, gopath string) error {
 	if err == nil && fileInfo.IsDir() {
 		expandedPath = filepath.Join(expandedPath, ".envrc")
 	}
+	existed := fileExists(expandedPath)
 
-	// Create the directory if it doesn't exist
-	err = os.MkdirAll(filepath.Dir(expandedPath), 0755)
+	// direnv evaluates .envrc files with bash
+	changed, err := updateRCFile(expandedPath, envrcBlock(shellBash.goEnv(goroot, gopath)), false)
 	if err != nil {
-		return fmt.Errorf("error creating directory for .envrc: %v", err)
+		return fmt.Errorf("error updating .envrc file: %v", err)
 	}
 
-	// Check if the file already exists
-	fileExists := false
-	if _, err := os.Stat(expandedPath); err == nil {
-		fileExists = true
-
-		// Check if Go environment variables are already set in the file
-		content, err := os.ReadFile(expandedPath)
-		if err != nil {
-			return fmt.Errorf("error reading existing .envrc file: %v", err)
-		}
-
-		if strings.Contains(string(content), "GOROOT=") {
-			color.Yellow("Go environment variables already exist in %s", expandedPath)
-			color.Yellow("Not modifying the existing .envrc file")
-			return nil
-		}
-	}
-
-	// Open the file in append mode if it exists, or create it if it doesn't
-	var f *os.File
-	if fileExists {
-		f, err = os.OpenFile(expandedPath, os.O_APPEND|os.O_WRONLY, 0644)
-		if err != nil {
-			return fmt.Errorf("error opening .envrc file: %v", err)
-		}
-
-		// Add a newline before our content if the file doesn't end with one
-		content, err := os.ReadFile(expandedPath)
-		if err != nil {
-			f.Close()
-			return fmt.Errorf("error reading .envrc file: %v", err)
-		}
-
-		if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
-			_, err = f.WriteString("\n")
-			if err != nil {
-				f.Close()
-				return fmt.Errorf("error writing to .envrc file: %v", err)
-			}
-		}
-	} else {
-		f, err = os.OpenFile(expandedPath, os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			return fmt.Errorf("error creating .envrc file: %v", err)
-		}
-	}
-	defer f.Close()
-
-	// Write the environment variables
-	_, err = f.WriteString("\n# Go environment variables added by getgo\n")
-	if err != nil {
-		return fmt.Errorf("error writing to .envrc file: %v", err)
-	}
-
-	// Write the exports based on the OS
-	if runtime.GOOS == "windows" {
-		_, err = f.WriteString(fmt.Sprintf("export GOROOT=\"%s\"\n", goroot))
-		if err != nil {
-			return fmt.Errorf("error writing to .envrc file: %v", err)
-		}
-
-		_, err = f.WriteString(fmt.Sprintf("export GOPATH=\"%s\"\n", gopath))
-		if err != nil {
-			return fmt.Errorf("error writing to .envrc file: %v", err)
-		}
-
-		_, err = f.WriteString("export PATH=\"$PATH:$GOPATH/bin:$GOROOT/bin\"\n")
-		if err != nil {
-			return fmt.Errorf("error writing to .envrc file: %v", err)
-		}
-	} else {
-		_, err = f.WriteString(fmt.Sprintf("export GOROOT=%s\n", goroot))
-		if err != nil {
-			return fmt.Errorf("error writing to .envrc file: %v", err)
-		}
-
-		_, err = f.WriteString(fmt.Sprintf("export GOPATH=%s\n", gopath))
-		if err != nil {
-			return fmt.Errorf("error writing to .envrc file: %v", err)
-		}
-
-		_, err = f.WriteString("export PATH=$PATH:$GOPATH/bin:$GOROOT/bin\n")
-		if err != nil {
-			return fmt.Errorf("error writing to .envrc file: %v", err)
-		}
-	}
-
-	if fileExists {
-		color.Green("Appended Go environment variables to existing .envrc file at %s", expandedPath)
-	} else {
+	switch {
+	case !changed:
+		color.Green("Go environment variables in %s already point to %s", expandedPath, goroot)
+	case existed:
+		color.Green("Go environment variables in %s now point to %s", expandedPath, goroot)
+		warnRCAssignments(expandedPath)
+	default:
 		color.Green("Created new .envrc file with Go environment variables at %s", expandedPath)
 	}
-
 	return nil
 }
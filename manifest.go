@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// defaultManifestTTL is how long a cached release manifest is used without refreshing it
+const defaultManifestTTL = time.Hour
+
+// offline disables all network access, set by --offline or GETGO_OFFLINE=1.
+// Versions are then resolved from the cached manifest and installed from the archive cache.
+var offline = os.Getenv("GETGO_OFFLINE") == "1" || strings.EqualFold(os.Getenv("GETGO_OFFLINE"), "true")
+
+// GoVersion is a release entry from the go.dev download manifest
+type GoVersion struct {
+	Version string   `json:"version"`
+	Stable  bool     `json:"stable"`
+	Files   []GoFile `json:"files"`
+}
+
+// GoFile is a single downloadable file of a Go release
+type GoFile struct {
+	Filename string `json:"filename"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Version  string `json:"version"`
+	SHA256   string `json:"sha256"`
+	Size     int64  `json:"size"`
+	Kind     string `json:"kind"` // "archive", "installer" or "source"
+}
+
+// fetchGoVersions downloads and decodes the release manifest at url
+func fetchGoVersions(ctx context.Context, url string) ([]GoVersion, error) {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status: %s (URL: %s)", resp.Status, url)
+	}
+
+	var versions []GoVersion
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("error decoding release manifest: %v", err)
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no Go versions found")
+	}
+
+	return versions, nil
+}
+
+// manifestCachePath returns where the release manifest is cached
+func manifestCachePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "manifest.json"), nil
+}
+
+// manifestTTL returns how long the cached manifest stays fresh (GETGO_MANIFEST_TTL or manifest_ttl in the config)
+func manifestTTL() time.Duration {
+	ttl := os.Getenv("GETGO_MANIFEST_TTL")
+	if ttl == "" {
+		if cfg, err := loadConfig(); err == nil {
+			ttl = cfg.ManifestTTL
+		}
+	}
+	if ttl != "" {
+		if d, err := parseAge(ttl); err == nil {
+			return d
+		}
+		color.Yellow("Ignoring invalid manifest TTL %q", ttl)
+	}
+	return defaultManifestTTL
+}
+
+// readCachedManifest decodes the cached release manifest at path
+func readCachedManifest(path string) ([]GoVersion, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var versions []GoVersion
+	if err := json.Unmarshal(content, &versions); err != nil {
+		return nil, fmt.Errorf("error decoding cached release manifest %s: %v", path, err)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("cached release manifest %s is empty", path)
+	}
+	return versions, nil
+}
+
+// writeCachedManifest stores the release manifest at path, replacing it atomically
+func writeCachedManifest(path string, versions []GoVersion) error {
+	content, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, content)
+}
+
+// loadManifest returns the full release manifest. A cached copy is used while it is younger
+// than the TTL, and as a fallback when the network is unavailable; in offline mode the cached
+// copy is the only source.
+func loadManifest(ctx context.Context) ([]GoVersion, error) {
+	path, err := manifestCachePath()
+	if err != nil {
+		if offline {
+			return nil, fmt.Errorf("offline: %v", err)
+		}
+		return fetchManifest(ctx)
+	}
+
+	if offline {
+		versions, err := readCachedManifest(path)
+		if err != nil {
+			return nil, fmt.Errorf("offline: release manifest is not cached at %s (run 'getgo list-remote' once while online): %v", path, err)
+		}
+		return versions, nil
+	}
+
+	info, statErr := os.Stat(path)
+	if statErr == nil && time.Since(info.ModTime()) < manifestTTL() {
+		if versions, err := readCachedManifest(path); err == nil {
+			return versions, nil
+		}
+	}
+
+	versions, err := fetchManifest(ctx)
+	if err != nil {
+		// An interrupted fetch is not a reason to fall back to the cached copy
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if cached, cacheErr := readCachedManifest(path); cacheErr == nil && statErr == nil {
+			color.Yellow("Could not refresh the release manifest (%v)", err)
+			color.Yellow("Using the cached copy from %s", info.ModTime().Format("2006-01-02 15:04"))
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	if err := writeCachedManifest(path, versions); err != nil {
+		color.Yellow("Could not cache the release manifest: %v", err)
+	}
+	return versions, nil
+}
+
+// findArchive returns the manifest entry of the archive for version on osName/arch
+func findArchive(versions []GoVersion, version, osName, arch string) (*GoFile, error) {
+	name := "go" + strings.TrimPrefix(version, "go")
+
+	// Accept both GOARCH and release file names, e.g. arm and armv6l
+	arch = goArch(arch)
+	platform := osName + "/" + arch
+
+	for _, v := range versions {
+		if v.Version != name {
+			continue
+		}
+		for i := range v.Files {
+			f := &v.Files[i]
+			if f.Kind == "archive" && f.OS == osName && f.Arch == releaseArch(arch) {
+				return f, nil
+			}
+		}
+
+		// Tell a platform without binary releases apart from one this release lacks
+		if all := releasePlatforms(versions); !slices.Contains(all, platform) {
+			return nil, fmt.Errorf("Go has no binary release for %s (available: %s)", platform, strings.Join(all, ", "))
+		}
+		return nil, fmt.Errorf("no archive of %s for %s in the release manifest (%s is available for %s)",
+			name, platform, name, strings.Join(releasePlatforms([]GoVersion{v}), ", "))
+	}
+
+	return nil, fmt.Errorf("%s not found in the release manifest", name)
+}
+
+// findArchiveByName returns the manifest entry of the release file with the given name, or nil
+func findArchiveByName(versions []GoVersion, filename string) *GoFile {
+	for _, v := range versions {
+		for i := range v.Files {
+			if v.Files[i].Filename == filename {
+				return &v.Files[i]
+			}
+		}
+	}
+	return nil
+}
+
+// checksumError is returned when an archive does not match its manifest entry
+type checksumError struct {
+	Filename string
+	What     string // "size" or "checksum"
+	Expected string
+	Got      string
+}
+
+func (e *checksumError) Error() string {
+	return fmt.Sprintf("%s mismatch for %s: expected %s, got %s", e.What, e.Filename, e.Expected, e.Got)
+}
+
+// verifyArchive checks the size and SHA-256 of the file at path against its manifest entry
+func verifyArchive(path string, file *GoFile) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	return matchDigest(file, n, h.Sum(nil))
+}
+
+// matchDigest compares the size and SHA-256 digest of downloaded data with its manifest entry
+func matchDigest(file *GoFile, size int64, digest []byte) error {
+	if file.Size > 0 && size != file.Size {
+		return &checksumError{Filename: file.Filename, What: "size",
+			Expected: fmt.Sprintf("%d bytes", file.Size), Got: fmt.Sprintf("%d bytes", size)}
+	}
+
+	sum := hex.EncodeToString(digest)
+	if !strings.EqualFold(sum, file.SHA256) {
+		return &checksumError{Filename: file.Filename, What: "checksum",
+			Expected: "sha256 " + file.SHA256, Got: sum}
+	}
+
+	return nil
+}
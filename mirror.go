@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// defaultMirror is the official download location of Go releases
+const defaultMirror = "https://go.dev/dl/"
+
+// manifestQuery selects the JSON manifest of all releases from a mirror
+const manifestQuery = "?mode=json&include=all"
+
+// mirrorFlag collects --mirror values, which take precedence over GETGO_MIRRORS and the config file
+var mirrorFlag listFlag
+
+// listFlag is a flag.Value for repeatable, comma-separated list flags
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, splitList(value)...)
+	return nil
+}
+
+// downloadMirrors returns the base URLs to fetch the manifest and archives from, in order
+func downloadMirrors() []string {
+	mirrors := []string(mirrorFlag)
+	if len(mirrors) == 0 {
+		mirrors = splitList(os.Getenv("GETGO_MIRRORS"))
+	}
+	if len(mirrors) == 0 {
+		if cfg, err := loadConfig(); err == nil {
+			mirrors = cfg.Mirrors
+		}
+	}
+	if len(mirrors) == 0 {
+		return []string{defaultMirror}
+	}
+
+	normalized := make([]string, 0, len(mirrors))
+	for _, mirror := range mirrors {
+		if !strings.HasSuffix(mirror, "/") {
+			mirror += "/"
+		}
+		normalized = append(normalized, mirror)
+	}
+	return normalized
+}
+
+// customMirrors reports whether mirrors other than go.dev are configured
+func customMirrors(mirrors []string) bool {
+	return len(mirrors) != 1 || mirrors[0] != defaultMirror
+}
+
+// isMirrorFailure reports whether err means the mirror is unusable, so the next one should be tried.
+// That is any connection-level error or 5xx response; other HTTP errors such as 404 are definitive.
+func isMirrorFailure(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	return true
+}
+
+// fetchManifest fetches the release manifest from the first mirror that answers
+func fetchManifest(ctx context.Context) ([]GoVersion, error) {
+	mirrors := downloadMirrors()
+
+	var err error
+	for i, mirror := range mirrors {
+		var versions []GoVersion
+		versions, err = fetchGoVersions(ctx, mirror+manifestQuery)
+		if err == nil {
+			if customMirrors(mirrors) {
+				color.Cyan("Release manifest served by %s", mirror)
+			}
+			return versions, nil
+		}
+		if ctx.Err() != nil || !isMirrorFailure(err) {
+			return nil, err
+		}
+		if i < len(mirrors)-1 {
+			color.Yellow("Mirror %s failed: %v", mirror, err)
+		}
+	}
+	return nil, err
+}
+
+// downloadFromMirrors downloads the file name to dest from the first mirror that serves it
+func downloadFromMirrors(ctx context.Context, name, dest string) error {
+	mirrors := downloadMirrors()
+
+	var err error
+	for i, mirror := range mirrors {
+		last := i == len(mirrors)-1
+
+		// Fail over quickly while other mirrors remain
+		attempts := maxDownloadAttempts
+		if !last {
+			attempts = 2
+		}
+
+		err = downloadFileWithProgress(ctx, mirror+name, dest, attempts)
+		if err == nil {
+			fmt.Println() // Add a newline after progress bar
+			color.Cyan("Downloaded %s from %s", name, mirror)
+			return nil
+		}
+		if ctx.Err() != nil || !isMirrorFailure(err) {
+			return err
+		}
+		if !last {
+			fmt.Println()
+			color.Yellow("Mirror %s failed: %v", mirror, err)
+			color.Yellow("Trying next mirror %s", mirrors[i+1])
+		}
+	}
+	return err
+}
+
+// streamFromMirrors streams and extracts the archive name into dst from the first mirror that
+// serves it, emptying dst again after each failed attempt
+func streamFromMirrors(ctx context.Context, name string, file *GoFile, dst string) error {
+	mirrors := downloadMirrors()
+
+	var err error
+	for i, mirror := range mirrors {
+		err = streamArchive(ctx, mirror+name, file, dst)
+		if err == nil {
+			color.Cyan("Streamed %s from %s", name, mirror)
+			return nil
+		}
+		fmt.Println()
+		if cleanErr := emptyDir(dst); cleanErr != nil {
+			return cleanErr
+		}
+		if ctx.Err() != nil || !isMirrorFailure(err) {
+			return err
+		}
+		if i < len(mirrors)-1 {
+			color.Yellow("Mirror %s failed: %v", mirror, err)
+			color.Yellow("Trying next mirror %s", mirrors[i+1])
+		}
+	}
+	return err
+}
+
+// emptyDir removes everything inside dir
+func emptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// releaseArchs maps GOARCH values to the architecture names of go.dev release files, where they differ
+var releaseArchs = map[string]string{
+	"arm": "armv6l",
+}
+
+// unameMachines maps `uname -m` output to GOARCH
+var unameMachines = map[string]string{
+	"x86_64":      "amd64",
+	"amd64":       "amd64",
+	"i386":        "386",
+	"i486":        "386",
+	"i586":        "386",
+	"i686":        "386",
+	"aarch64":     "arm64",
+	"arm64":       "arm64",
+	"armv6l":      "arm",
+	"armv7l":      "arm",
+	"armv8l":      "arm", // 32-bit userland on a 64-bit ARM CPU
+	"arm":         "arm",
+	"ppc64le":     "ppc64le",
+	"ppc64":       "ppc64",
+	"s390x":       "s390x",
+	"riscv64":     "riscv64",
+	"loongarch64": "loong64",
+	"loong64":     "loong64",
+}
+
+// windowsArchs maps PROCESSOR_ARCHITECTURE values to GOARCH
+var windowsArchs = map[string]string{
+	"AMD64": "amd64",
+	"ARM64": "arm64",
+	"x86":   "386",
+}
+
+// hostPlatform returns the GOOS and GOARCH of the machine getgo runs on, detected once
+var hostPlatform = sync.OnceValues(detectHostPlatform)
+
+// releaseArch returns the architecture name go.dev uses in release file names for a GOARCH
+func releaseArch(arch string) string {
+	if name, ok := releaseArchs[arch]; ok {
+		return name
+	}
+	return arch
+}
+
+// goArch returns the GOARCH for an architecture name of a release file
+func goArch(name string) string {
+	for arch, releaseName := range releaseArchs {
+		if releaseName == name {
+			return arch
+		}
+	}
+	return name
+}
+
+// detectHostPlatform works out the platform toolchains are installed for. This is not always
+// the one getgo was built for: an amd64 getgo may run under Rosetta 2 or qemu on an arm64
+// machine, and a 64-bit kernel may run a 32-bit userland that cannot run 64-bit toolchains.
+func detectHostPlatform() (string, string) {
+	goos := runtime.GOOS
+	if goos == "windows" {
+		return goos, windowsArch()
+	}
+
+	arch := unameArch()
+	switch goos {
+	case "darwin":
+		// Processes translated by Rosetta 2 see x86_64 from uname
+		if arch == "amd64" && isRosettaTranslated() {
+			arch = "arm64"
+		}
+	case "linux":
+		// The shell binary tells what the userland runs, which uname does not under qemu
+		// or with a 32-bit userland on a 64-bit kernel
+		if userland := userlandArch("/bin/sh"); userland != "" {
+			arch = userland
+		}
+	}
+	return goos, arch
+}
+
+// unameArch returns the GOARCH for the machine reported by `uname -m`, or the GOARCH getgo
+// was built for if uname is unavailable or reports an unknown machine
+func unameArch() string {
+	out, err := exec.Command("uname", "-m").Output()
+	if err != nil {
+		return runtime.GOARCH
+	}
+	if arch, ok := unameMachines[strings.TrimSpace(string(out))]; ok {
+		return arch
+	}
+	return runtime.GOARCH
+}
+
+// isRosettaTranslated reports whether the process runs under Rosetta 2 on an Apple silicon Mac
+func isRosettaTranslated() bool {
+	out, err := exec.Command("sysctl", "-n", "sysctl.proc_translated").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "1"
+}
+
+// userlandArch returns the GOARCH of the ELF executable at path, or "" if it cannot be told
+func userlandArch(path string) string {
+	f, err := elf.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	is64 := f.Class == elf.ELFCLASS64
+	little := f.ByteOrder == binary.LittleEndian
+	switch f.Machine {
+	case elf.EM_X86_64:
+		if is64 {
+			return "amd64"
+		}
+	case elf.EM_386:
+		return "386"
+	case elf.EM_AARCH64:
+		if is64 {
+			return "arm64"
+		}
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_PPC64:
+		if little {
+			return "ppc64le"
+		}
+		return "ppc64"
+	case elf.EM_S390:
+		if is64 {
+			return "s390x"
+		}
+	case elf.EM_RISCV:
+		if is64 {
+			return "riscv64"
+		}
+	case elf.EM_LOONGARCH:
+		if is64 {
+			return "loong64"
+		}
+	case elf.EM_MIPS:
+		switch {
+		case is64 && little:
+			return "mips64le"
+		case is64:
+			return "mips64"
+		case little:
+			return "mipsle"
+		}
+		return "mips"
+	}
+	return ""
+}
+
+// windowsArch returns the GOARCH of Windows. Emulated processes see the emulated architecture
+// in the environment, so the native machine is asked for first. Where that is unavailable, a
+// 32-bit process on 64-bit Windows sees x86 in PROCESSOR_ARCHITECTURE and the real architecture
+// in PROCESSOR_ARCHITEW6432.
+func windowsArch() string {
+	if arch := nativeMachineArch(); arch != "" {
+		return arch
+	}
+	name := os.Getenv("PROCESSOR_ARCHITEW6432")
+	if name == "" {
+		name = os.Getenv("PROCESSOR_ARCHITECTURE")
+	}
+	if arch, ok := windowsArchs[name]; ok {
+		return arch
+	}
+	return runtime.GOARCH
+}
+
+// releasePlatforms returns the platforms with an archive in the given releases, as GOOS/GOARCH
+func releasePlatforms(versions []GoVersion) []string {
+	var platforms []string
+	for _, v := range versions {
+		for _, f := range v.Files {
+			if f.Kind != "archive" {
+				continue
+			}
+			platform := fmt.Sprintf("%s/%s", f.OS, goArch(f.Arch))
+			if !slices.Contains(platforms, platform) {
+				platforms = append(platforms, platform)
+			}
+		}
+	}
+	slices.Sort(platforms)
+	return platforms
+}
@@ -0,0 +1,38 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given ID is running
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// parentProcessName returns the command name of the parent process, or "" if it cannot be told
+func parentProcessName() string {
+	ppid := os.Getppid()
+	if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", ppid)); err == nil {
+		return strings.TrimSpace(string(comm))
+	}
+	// Systems without procfs, like macOS and the BSDs
+	out, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(ppid)).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// nativeMachineArch is only implemented on Windows, where windowsArch needs it
+func nativeMachineArch() string {
+	return ""
+}
@@ -0,0 +1,60 @@
+//go:build windows
+
+package main
+
+import (
+	"debug/pe"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// windowsMachines maps the image file machine types of IsWow64Process2 to GOARCH
+var windowsMachines = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_AMD64: "amd64",
+	pe.IMAGE_FILE_MACHINE_ARM64: "arm64",
+	pe.IMAGE_FILE_MACHINE_I386:  "386",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "arm",
+}
+
+// processAlive reports whether a process with the given ID is running
+func processAlive(pid int) bool {
+	// FindProcess opens the process on Windows, which fails once it has exited
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
+
+// parentProcessName returns the executable name of the parent process, or "" if it cannot be told
+func parentProcessName() string {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return ""
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	ppid := uint32(os.Getppid())
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		if entry.ProcessID == ppid {
+			return syscall.UTF16ToString(entry.ExeFile[:])
+		}
+	}
+	return ""
+}
+
+// nativeMachineArch returns the GOARCH of the machine Windows runs on, even to an emulated process
+// such as an amd64 getgo on arm64 Windows, or "" on Windows versions without IsWow64Process2
+func nativeMachineArch() string {
+	var processMachine, nativeMachine uint16
+	if err := windows.IsWow64Process2(windows.CurrentProcess(), &processMachine, &nativeMachine); err != nil {
+		return ""
+	}
+	return windowsMachines[nativeMachine]
+}
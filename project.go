@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// versionRequirement is a Go version requested by a project file
+type versionRequirement struct {
+	Spec    string // Version spec without the "go" prefix, e.g. "1.22.3" or "1.22"
+	Minimum bool   // Set for go directives, which accept any later release of the same series
+	Source  string // File the requirement was read from
+}
+
+// String describes the requirement for messages
+func (r *versionRequirement) String() string {
+	if r.Minimum {
+		return fmt.Sprintf("go >= %s (%s)", r.Spec, r.Source)
+	}
+	return fmt.Sprintf("go %s (%s)", r.Spec, r.Source)
+}
+
+// installSpec returns the version spec to install when the requirement is not yet satisfied
+func (r *versionRequirement) installSpec() string {
+	// A go directive is satisfied by the newest patch release of its series
+	if r.Minimum && !isPrerelease(r.Spec) {
+		return strings.TrimPrefix(goSeries(r.Spec), "go")
+	}
+	return r.Spec
+}
+
+// parseGoDirectives returns the go and toolchain versions declared in a go.mod or go.work file
+func parseGoDirectives(content string) (goVersion, toolchain string) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "go":
+			goVersion = fields[1]
+		case "toolchain":
+			// "toolchain default" means no specific toolchain
+			if strings.HasPrefix(fields[1], "go") {
+				toolchain = strings.TrimPrefix(fields[1], "go")
+			}
+		}
+	}
+	return goVersion, toolchain
+}
+
+// readVersionFile reads the version requirement from a project file, based on its name.
+// It returns nil if the file does not exist or does not declare a version.
+func readVersionFile(path string) (*versionRequirement, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Base(path) {
+	case "go.mod", "go.work":
+		// The toolchain directive names the exact toolchain, the go directive a minimum
+		goVersion, toolchain := parseGoDirectives(string(content))
+		if toolchain != "" {
+			return &versionRequirement{Spec: toolchain, Source: path}, nil
+		}
+		if goVersion != "" {
+			return &versionRequirement{Spec: goVersion, Minimum: true, Source: path}, nil
+		}
+
+	case ".tool-versions":
+		// asdf lists one tool per line: "golang 1.22.3 [fallback versions...]"
+		for _, line := range strings.Split(string(content), "\n") {
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && (fields[0] == "golang" || fields[0] == "go") {
+				return &versionRequirement{Spec: strings.TrimPrefix(fields[1], "go"), Source: path}, nil
+			}
+		}
+
+	default:
+		// .go-version holds a single version, optionally with a "go" prefix
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			return &versionRequirement{Spec: strings.TrimPrefix(line, "go"), Source: path}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findVersionRequirement walks up from dir and returns the first version requirement found.
+// In each directory the files are checked in the given order; nil means none was found.
+func findVersionRequirement(dir string, order []string) (*versionRequirement, error) {
+	for {
+		for _, name := range order {
+			req, err := readVersionFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			if req != nil {
+				return req, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// matchInstalled returns the installed toolchain under installPath that satisfies req
+func matchInstalled(installPath string, req *versionRequirement) (*installedGo, error) {
+	if !req.Minimum {
+		return resolveInstalled(installPath, req.Spec)
+	}
+
+	installed, err := findInstalledGo(installPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Installed toolchains are sorted newest first
+	for i := range installed {
+		v := installed[i].Version
+		if goSeries(v) == goSeries(req.Spec) && compareGoVersions(v, req.Spec) >= 0 {
+			return &installed[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no installed Go toolchain in %s satisfies %s", installPath, req)
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// toolchainModule is the module the go command downloads toolchains as
+	toolchainModule = "golang.org/toolchain"
+
+	// toolchainModulePrefix starts every toolchain module version, e.g. v0.0.1-go1.22.3.linux-amd64
+	toolchainModulePrefix = "v0.0.1-go"
+
+	// defaultGOPROXY is the GOPROXY value the go command uses when none is set
+	defaultGOPROXY = "https://proxy.golang.org,direct"
+)
+
+// Download sources
+const (
+	sourceDL    = "dl"    // Release archives from go.dev/dl or the configured mirrors
+	sourceProxy = "proxy" // Toolchain modules from GOPROXY
+)
+
+// sourceFlag is set by --source and takes precedence over GETGO_SOURCE and the config file
+var sourceFlag string
+
+// errDirect is returned when GOPROXY asks for a direct download, which means the dl source
+var errDirect = errors.New("GOPROXY requests a direct download")
+
+// errProxyOff is returned when GOPROXY=off forbids downloads
+var errProxyOff = errors.New(toolchainModule + " downloads disabled by GOPROXY=off")
+
+// downloadSource returns the configured download source
+func downloadSource() (string, error) {
+	source := sourceFlag
+	if source == "" {
+		source = os.Getenv("GETGO_SOURCE")
+	}
+	if source == "" {
+		if cfg, err := loadConfig(); err == nil {
+			source = cfg.Source
+		}
+	}
+
+	switch source {
+	case "":
+		return sourceDL, nil
+	case sourceDL, sourceProxy:
+		return source, nil
+	}
+	return "", fmt.Errorf("unknown download source %q (expected %s or %s)", source, sourceDL, sourceProxy)
+}
+
+// loadReleases returns the available releases from the configured download source.
+// Offline mode always uses the cached release manifest.
+func loadReleases(ctx context.Context) ([]GoVersion, error) {
+	source, err := downloadSource()
+	if err != nil {
+		return nil, err
+	}
+	if source == sourceProxy && !offline {
+		versions, err := proxyVersions(ctx)
+		if !errors.Is(err, errDirect) {
+			return versions, err
+		}
+	}
+	return loadManifest(ctx)
+}
+
+// proxyEntry is one element of the GOPROXY list
+type proxyEntry struct {
+	url         string // Proxy base URL, "direct" or "off"
+	fallBackAll bool   // Followed by "|": try the next entry on any error, not only 404 and 410
+}
+
+// parseGOPROXY splits a GOPROXY value into its entries
+func parseGOPROXY(value string) []proxyEntry {
+	var entries []proxyEntry
+	for value != "" {
+		var entry proxyEntry
+		if i := strings.IndexAny(value, ",|"); i >= 0 {
+			entry.url, entry.fallBackAll = value[:i], value[i] == '|'
+			value = value[i+1:]
+		} else {
+			entry.url, value = value, ""
+		}
+		if entry.url = strings.TrimSpace(entry.url); entry.url != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// matchModulePatterns reports whether any of the comma-separated glob patterns matches a
+// prefix of the module path, as GONOPROXY, GONOSUMDB and GOPRIVATE patterns do
+func matchModulePatterns(patterns, module string) bool {
+	for _, pattern := range splitList(patterns) {
+		n := strings.Count(pattern, "/") + 1
+		elems := strings.SplitN(module, "/", n+1)
+		if len(elems) < n {
+			continue
+		}
+		if ok, _ := path.Match(pattern, strings.Join(elems[:n], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// goproxyEntries returns the proxies to fetch toolchains from, honoring GOPROXY and GONOPROXY
+func goproxyEntries() ([]proxyEntry, error) {
+	noProxy, ok := os.LookupEnv("GONOPROXY")
+	if !ok {
+		noProxy = os.Getenv("GOPRIVATE")
+	}
+	if matchModulePatterns(noProxy, toolchainModule) {
+		return []proxyEntry{{url: "direct"}}, nil
+	}
+
+	value := os.Getenv("GOPROXY")
+	if value == "" {
+		value = defaultGOPROXY
+	}
+	entries := parseGOPROXY(value)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("GOPROXY list contains no entries: %q", value)
+	}
+	return entries, nil
+}
+
+// isProxyNotFound reports whether a proxy answered that it does not have the requested file
+func isProxyNotFound(err error) bool {
+	var statusErr *httpStatusError
+	return errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone)
+}
+
+// walkProxies calls fetch with the base URL of each GOPROXY entry until one succeeds.
+// Like the go command it moves on after a 404 or 410 answer, or after any error if the entry
+// is followed by "|". Reaching "direct" returns errDirect and reaching "off" fails. Once ctx
+// is canceled no further entries are tried.
+func walkProxies(ctx context.Context, fetch func(base string) error) error {
+	entries, err := goproxyEntries()
+	if err != nil {
+		return err
+	}
+
+	err = nil
+	for _, entry := range entries {
+		switch entry.url {
+		case "direct":
+			return errDirect
+		case "off":
+			if err != nil {
+				return err
+			}
+			return errProxyOff
+		}
+
+		base := strings.TrimSuffix(entry.url, "/")
+		if err = fetch(base); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !entry.fallBackAll && !isProxyNotFound(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// proxyGet fetches a small file, such as a version list, from a proxy
+func proxyGet(ctx context.Context, url string) ([]byte, error) {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, URL: url}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// toolchainModuleVersion returns the module version of a toolchain, e.g. v0.0.1-go1.22.3.linux-amd64
+func toolchainModuleVersion(version, osName, arch string) string {
+	return fmt.Sprintf("%s%s.%s-%s", toolchainModulePrefix, version, osName, arch)
+}
+
+// parseToolchainModuleVersion splits a toolchain module version into Go version, OS and architecture
+func parseToolchainModuleVersion(modVersion string) (version, osName, arch string, ok bool) {
+	rest, ok := strings.CutPrefix(modVersion, toolchainModulePrefix)
+	if !ok {
+		return "", "", "", false
+	}
+	i := strings.LastIndex(rest, ".")
+	if i < 0 {
+		return "", "", "", false
+	}
+	version = rest[:i]
+	osName, arch, ok = strings.Cut(rest[i+1:], "-")
+	if !ok || !isConcreteVersion(version) {
+		return "", "", "", false
+	}
+	return version, osName, arch, true
+}
+
+// proxyVersions lists the toolchains available from GOPROXY in the shape of the release manifest,
+// so version resolution and list-remote work the same for both sources. The listed files carry
+// no checksums.
+func proxyVersions(ctx context.Context) ([]GoVersion, error) {
+	var body []byte
+	var proxy string
+	err := walkProxies(ctx, func(base string) error {
+		var err error
+		proxy = base
+		body, err = proxyGet(ctx, base+"/"+toolchainModule+"/@v/list")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[string]*GoVersion{}
+	var versions []*GoVersion
+	for _, modVersion := range strings.Fields(string(body)) {
+		version, osName, arch, ok := parseToolchainModuleVersion(modVersion)
+		if !ok {
+			continue
+		}
+		v := byVersion[version]
+		if v == nil {
+			v = &GoVersion{Version: "go" + version, Stable: !isPrerelease(version)}
+			byVersion[version] = v
+			versions = append(versions, v)
+		}
+		v.Files = append(v.Files, GoFile{
+			Filename: modVersion + ".zip",
+			OS:       osName,
+			Arch:     releaseArch(arch),
+			Version:  v.Version,
+			Kind:     "archive",
+		})
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no Go toolchains listed by %s", proxy)
+	}
+
+	color.Cyan("Toolchains listed by %s", proxy)
+	result := make([]GoVersion, len(versions))
+	for i, v := range versions {
+		result[i] = *v
+	}
+	return result, nil
+}
+
+// installFromProxy downloads a toolchain module from GOPROXY and unpacks it as destDir/go.
+// It returns errDirect if GOPROXY says to download the release archive instead.
+func installFromProxy(ctx context.Context, version, osName, arch string, noVerify bool, destDir string) error {
+	modVersion := toolchainModuleVersion(version, osName, arch)
+	zipPath := filepath.Join(destDir, modVersion+".zip")
+
+	err := walkProxies(ctx, func(base string) error {
+		url := base + "/" + toolchainModule + "/@v/" + modVersion
+
+		// The .info file tells whether the proxy has the version before downloading it
+		if _, err := proxyGet(ctx, url+".info"); err != nil {
+			return err
+		}
+
+		// Look up the expected checksum before downloading anything
+		var hash string
+		if !noVerify {
+			var err error
+			hash, err = lookupToolchainHash(ctx, modVersion)
+			switch {
+			case errors.Is(err, errNoSumDB):
+				// Like the go command, trust the proxy for modules excluded from the database
+				color.Yellow("Not verifying %s@%s: %v", toolchainModule, modVersion, err)
+			case err != nil:
+				return fmt.Errorf("%v (use --no-verify to skip checksum verification)", err)
+			}
+		}
+
+		color.Cyan("Downloading %s@%s from %s ...", toolchainModule, modVersion, base)
+		if err := downloadFileWithProgress(ctx, url+".zip", zipPath, maxDownloadAttempts); err != nil {
+			return err
+		}
+		fmt.Println() // Add a newline after progress bar
+
+		if hash != "" {
+			if err := verifyModuleZip(zipPath, hash); err != nil {
+				os.Remove(zipPath)
+				return err
+			}
+			color.Green("Checksum verified: %s", hash)
+		}
+		return nil
+	})
+	if errors.Is(err, errDirect) || ctx.Err() != nil {
+		return err
+	}
+	if isProxyNotFound(err) {
+		return fmt.Errorf("Go version %s not found for %s/%s on GOPROXY: %v", version, osName, arch, err)
+	}
+	if err != nil {
+		return fmt.Errorf("error downloading Go toolchain module: %v", err)
+	}
+	defer os.Remove(zipPath)
+
+	color.Cyan("Extracting %s@%s ...", toolchainModule, modVersion)
+	if err := unzipModule(ctx, zipPath, toolchainModule+"@"+modVersion+"/", filepath.Join(destDir, "go")); err != nil {
+		return fmt.Errorf("error extracting toolchain module: %v", err)
+	}
+	return nil
+}
+
+// unzipModule extracts a toolchain module zip into dst, stripping the module@version/ prefix
+// so the files are laid out like an extracted release archive
+func unzipModule(ctx context.Context, src, prefix, dst string) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	e, err := newExtractor(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range r.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok {
+			return fmt.Errorf("unexpected file %s outside of %s", f.Name, prefix)
+		}
+		// The module's go.mod is not part of a Go release tree
+		if name == "" || name == "go.mod" {
+			continue
+		}
+
+		// Module zips do not record file modes, so mark the tools executable like the go command does
+		perm := os.FileMode(0644)
+		if strings.HasPrefix(name, "bin/") || strings.HasPrefix(name, "pkg/tool/") {
+			perm = 0755
+		}
+
+		if err := extractZipEntry(e, f, name, perm); err != nil {
+			return err
+		}
+	}
+	return e.finish()
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// rcBlockBegin and rcBlockEnd enclose the lines getgo manages in a shell configuration file
+	rcBlockBegin = "# >>> getgo >>>"
+	rcBlockEnd   = "# <<< getgo <<<"
+
+	// rcBlockNote and envrcBlockNote are the first line of the managed block in shell configuration
+	// files and .envrc files
+	rcBlockNote    = "# Managed by getgo, edits inside this block are overwritten. Remove it with 'getgo env --remove'."
+	envrcBlockNote = "# Managed by getgo, edits inside this block are overwritten. Update it with 'getgo sync'."
+
+	// legacyRCComment starts the exports that getgo appended before it managed a block
+	legacyRCComment = "# Go environment variables added by getgo"
+
+	// rcBackupSuffix is appended to the name of a shell configuration file to name its backup
+	rcBackupSuffix = ".getgo.bak"
+)
+
+// rcBlock renders the managed block of a shell configuration file holding lines
+func rcBlock(lines []string) string {
+	return managedBlock(rcBlockNote, lines)
+}
+
+// envrcBlock renders the managed block of a .envrc file holding lines
+func envrcBlock(lines []string) string {
+	return managedBlock(envrcBlockNote, lines)
+}
+
+// managedBlock renders a block of lines between the getgo markers, starting with note
+func managedBlock(note string, lines []string) string {
+	var sb strings.Builder
+	sb.WriteString(rcBlockBegin + "\n")
+	sb.WriteString(note + "\n")
+	for _, line := range lines {
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString(rcBlockEnd + "\n")
+	return sb.String()
+}
+
+// findRCBlock returns the first and last line of the managed block. Unbalanced markers are
+// an error, as rewriting the file could then lose lines the user wrote.
+func findRCBlock(lines []string) (start, end int, found bool, err error) {
+	start = -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case rcBlockBegin:
+			if start >= 0 {
+				return 0, 0, false, fmt.Errorf("getgo block started on line %d is not closed before line %d", start+1, i+1)
+			}
+			start = i
+		case rcBlockEnd:
+			if start < 0 {
+				return 0, 0, false, fmt.Errorf("end of a getgo block on line %d has no beginning", i+1)
+			}
+			return start, i, true, nil
+		}
+	}
+	if start >= 0 {
+		return 0, 0, false, fmt.Errorf("getgo block started on line %d is never closed (missing %q)", start+1, rcBlockEnd)
+	}
+	return 0, 0, false, nil
+}
+
+// findLegacyRCBlock returns the first and last line of the exports older getgo versions
+// appended: their comment followed by the GOROOT, GOPATH and PATH exports
+func findLegacyRCBlock(lines []string) (start, end int, found bool) {
+	for i, line := range lines {
+		if line != legacyRCComment {
+			continue
+		}
+		end = i
+		for _, name := range []string{"GOROOT=", "GOPATH=", "PATH="} {
+			if end+1 < len(lines) && strings.HasPrefix(lines[end+1], "export "+name) {
+				end++
+			}
+		}
+		return i, end, true
+	}
+	return 0, 0, false
+}
+
+// joinLines joins lines into text with a trailing newline
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// replaceRCBlock returns content with its managed block, or the exports of an older getgo,
+// replaced by block. The block is appended if there is none, and removed if block is empty.
+func replaceRCBlock(content, block string) (string, error) {
+	lines := splitLines(content)
+	start, end, found, err := findRCBlock(lines)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		start, end, found = findLegacyRCBlock(lines)
+	}
+
+	if !found {
+		if block == "" {
+			return content, nil
+		}
+		// Separate the block from what precedes it
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		return joinLines(append(lines, splitLines(block)...)), nil
+	}
+
+	// Removing the block also removes the blank line added in front of it
+	if block == "" && start > 0 && strings.TrimSpace(lines[start-1]) == "" {
+		start--
+	}
+	out := append(lines[:start:start], splitLines(block)...)
+	return joinLines(append(out, lines[end+1:]...)), nil
+}
+
+// goBinDir matches a Go bin directory written out, such as ~/go/bin or /usr/local/go1.22.3/bin
+var goBinDir = regexp.MustCompile(`[/\\]go(\d[\w.-]*)?[/\\]bin\b`)
+
+// rcLinesOutsideBlock returns the numbers of the lines outside the managed block that match,
+// skipping comments
+func rcLinesOutsideBlock(content string, match func(line string) bool) []int {
+	lines := splitLines(content)
+	start, end, found, _ := findRCBlock(lines)
+
+	var numbers []int
+	for i, line := range lines {
+		if found && i >= start && i <= end {
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match(line) {
+			numbers = append(numbers, i+1)
+		}
+	}
+	return numbers
+}
+
+// setsGoEnv reports whether a shell line sets GOROOT or GOPATH or adds a Go bin directory to
+// PATH, as such lines outside the managed block may override or duplicate it
+func setsGoEnv(line string) bool {
+	return assignsVariable(line, "GOROOT") || assignsVariable(line, "GOPATH") || addsGoBinDir(line)
+}
+
+// addsGoBinDir reports whether a shell line puts a Go bin directory, given literally or through
+// GOROOT or GOPATH, into PATH
+func addsGoBinDir(line string) bool {
+	fields := strings.Fields(line)
+	setsPath := assignsVariable(line, "PATH") ||
+		// fish_add_path, and the path lists of elvish and csh
+		len(fields) > 0 && fields[0] == "fish_add_path" ||
+		len(fields) > 1 && fields[0] == "set" && (fields[1] == "paths" || fields[1] == "path")
+	return setsPath && (strings.Contains(line, "GOROOT") || strings.Contains(line, "GOPATH") || goBinDir.MatchString(line))
+}
+
+// assignsVariable reports whether a shell line sets the variable name, in the syntax of any
+// shell getgo writes for
+func assignsVariable(line, name string) bool {
+	// The name must not be the end of a longer one, like PATH in GOPATH=
+	for rest := line; ; {
+		i := strings.Index(rest, name+"=")
+		if i < 0 {
+			break
+		}
+		if i == 0 || !isNameChar(rest[i-1]) {
+			return true
+		}
+		rest = rest[i+len(name):]
+	}
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		switch field {
+		case "$env." + name, "$env:" + name, "E:" + name:
+			// nushell, PowerShell and elvish
+			if i+1 < len(fields) && (fields[i+1] == "=" || fields[i+1] == "+=") {
+				return true
+			}
+		case name:
+			// fish set and csh setenv
+			if i > 0 && (fields[0] == "set" || fields[0] == "setenv") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isNameChar reports whether c may appear in an environment variable name
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// updateRCFile puts block into the managed block of the file at path, or removes the block if
+// it is empty, and reports whether the file changed. A backup of the previous content is written
+// first. With dryRun, the changes are printed as a diff instead.
+func updateRCFile(path, block string, dryRun bool) (bool, error) {
+	// Edit the target of a symlink, as dotfile managers link configuration files
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	content, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	newContent, err := replaceRCBlock(string(content), block)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", path, err)
+	}
+	if newContent == string(content) {
+		return false, nil
+	}
+
+	if dryRun {
+		printDiff(unifiedDiff(path, string(content), newContent))
+		return true, nil
+	}
+
+	if !exists {
+		if err := writeFileAtomic(path, []byte(newContent)); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return true, writeRCFile(path, string(content), newContent)
+}
+
+// writeRCFile replaces the content of an existing configuration file at path, which must not be
+// a symlink, with newContent. The previous content is saved as a backup first, and the file keeps
+// its permissions.
+func writeRCFile(path, content, newContent string) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	backup := path + rcBackupSuffix
+	if err := os.WriteFile(backup, []byte(content), mode); err != nil {
+		return fmt.Errorf("error writing backup %s: %v", backup, err)
+	}
+	color.Cyan("Saved the previous %s as %s", filepath.Base(path), backup)
+
+	if err := writeFileAtomic(path, []byte(newContent)); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// printDiff prints a unified diff with added lines in green and removed lines in red
+func printDiff(diff string) {
+	for _, line := range splitLines(diff) {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			color.New(color.Bold).Println(line)
+		case strings.HasPrefix(line, "@@"):
+			color.Cyan("%s", line)
+		case strings.HasPrefix(line, "+"):
+			color.Green("%s", line)
+		case strings.HasPrefix(line, "-"):
+			color.Red("%s", line)
+		default:
+			fmt.Println(line)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// printListRemoteUsage prints the usage information for the list-remote command
+func printListRemoteUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Printf("%s: getgo list-remote [options] [install_path]\n", bold("Usage"))
+	fmt.Printf("%s:\n", bold("Examples"))
+	fmt.Printf("  %s         # All Go releases\n", cyan("getgo list-remote"))
+	fmt.Printf("  %s    # Releases of Go 1.22\n", cyan("getgo list-remote 1.22"))
+	fmt.Printf("  %s    # Release candidates and betas\n", cyan("getgo list-remote --rc"))
+	fmt.Printf("  %s   # Mark versions installed in ~/.go\n", cyan("getgo list-remote ~/.go"))
+
+	fmt.Printf("\n%s:\n", bold("Options"))
+	fmt.Printf("  --series SERIES    Only show releases of a minor series (e.g. 1.22)\n")
+	fmt.Printf("  --stable           Only show stable releases\n")
+	fmt.Printf("  --rc               Only show prereleases (betas and release candidates)\n")
+	hostOS, hostArch := hostPlatform()
+	fmt.Printf("  --os OS            Check archive availability for OS (default: %s)\n", hostOS)
+	fmt.Printf("  --arch ARCH        Check archive availability for ARCH (default: %s)\n", hostArch)
+	fmt.Printf("  --offline          Use the cached release manifest only\n")
+	fmt.Printf("  --mirror URL       Download mirror to try before falling back to the next (or GETGO_MIRRORS)\n")
+	fmt.Printf("  --source SOURCE    List releases from dl (go.dev or mirrors) or proxy (GOPROXY)\n")
+}
+
+// runListRemote lists the Go releases available for download
+func runListRemote(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("list-remote", flag.ExitOnError)
+	fs.Usage = printListRemoteUsage
+	seriesFlag := fs.String("series", "", "Only show releases of a minor series")
+	stableFlag := fs.Bool("stable", false, "Only show stable releases")
+	rcFlag := fs.Bool("rc", false, "Only show prereleases")
+	hostOS, hostArch := hostPlatform()
+	osFlag := fs.String("os", hostOS, "Operating system to check archives for")
+	archFlag := fs.String("arch", hostArch, "Architecture to check archives for")
+	fs.BoolVar(&offline, "offline", offline, "Use the cached release manifest only")
+	fs.Var(&mirrorFlag, "mirror", "Download mirror base URL (repeatable, comma-separated)")
+	fs.StringVar(&sourceFlag, "source", "", "List releases from dl (go.dev or mirrors) or proxy (GOPROXY)")
+	args = parseArgs(fs, args)
+
+	// A leading series argument is a shorthand for --series
+	series := *seriesFlag
+	if len(args) > 0 && seriesSpec.MatchString(strings.TrimPrefix(args[0], "go")) {
+		series = args[0]
+		args = args[1:]
+	}
+
+	installPath := "."
+	switch len(args) {
+	case 0:
+	case 1:
+		installPath = args[0]
+	default:
+		printListRemoteUsage()
+		os.Exit(1)
+	}
+	installPath = expandPathOrExit(installPath)
+
+	color.Cyan("Fetching release manifest...")
+	versions, err := loadReleases(ctx)
+	exitIfCanceled(ctx)
+	if err != nil {
+		color.Red("Error fetching release manifest: %v", err)
+		os.Exit(1)
+	}
+
+	// Mark the versions the resolver would pick, so the listing agrees with installs
+	notes := map[string][]string{}
+	for _, spec := range []string{"latest", "oldstable", series} {
+		if spec == "" {
+			continue
+		}
+		if v, err := resolveVersion(spec, versions); err == nil {
+			notes[v] = append(notes[v], strings.TrimPrefix(spec, "go"))
+		}
+	}
+
+	seriesName := ""
+	if series != "" {
+		m := seriesSpec.FindStringSubmatch(strings.TrimPrefix(series, "go"))
+		if m == nil {
+			color.Red("Invalid series %q (expected e.g. 1.22)", series)
+			os.Exit(1)
+		}
+		seriesName = "go" + m[1]
+	}
+
+	// Toolchains are recognized by their VERSION file, as for the other commands, and those
+	// installed for the --os and --arch platform by their qualified directory name
+	installedVersions := map[string]bool{}
+	osName, arch, foreign := installOptions{osName: *osFlag, arch: *archFlag}.platform()
+	if installed, err := findInstalledGoFor(installPath, osName, arch, foreign); err == nil {
+		for _, inst := range installed {
+			installedVersions[inst.Version] = true
+		}
+	}
+
+	platform := fmt.Sprintf("%s/%s", *osFlag, *archFlag)
+	bold := color.New(color.Bold).SprintFunc()
+	fmt.Printf("\n%s\n", bold(fmt.Sprintf("%-14s %-9s %-14s %-10s %s", "VERSION", "STATUS", platform, "INSTALLED", "NOTES")))
+
+	shown := 0
+	for _, v := range sortedGoVersions(versions) {
+		version := strings.TrimPrefix(v.Version, "go")
+		if seriesName != "" && goSeries(v.Version) != seriesName {
+			continue
+		}
+		if *stableFlag && !v.Stable {
+			continue
+		}
+		if *rcFlag && !isPrerelease(v.Version) {
+			continue
+		}
+
+		status := "unstable"
+		if v.Stable {
+			status = "stable"
+		}
+
+		available := "-"
+		if _, err := findArchive([]GoVersion{v}, version, *osFlag, *archFlag); err == nil {
+			available = "yes"
+		}
+
+		installed := "-"
+		if installedVersions[version] {
+			installed = "yes"
+		}
+
+		line := fmt.Sprintf("%-14s %-9s %-14s %-10s %s", version, status, available, installed, strings.Join(notes[version], ", "))
+		line = strings.TrimRight(line, " ")
+		switch {
+		case installed == "yes":
+			color.Green("%s", line)
+		case !v.Stable:
+			color.Yellow("%s", line)
+		default:
+			fmt.Println(line)
+		}
+		shown++
+	}
+
+	if shown == 0 {
+		color.Yellow("No Go releases match the given filters")
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// shell identifies a shell by the syntax and configuration file getgo writes for it
+type shell string
+
+const (
+	shellSh     shell = "sh"
+	shellBash   shell = "bash"
+	shellZsh    shell = "zsh"
+	shellFish   shell = "fish"
+	shellNu     shell = "nu"
+	shellPwsh   shell = "pwsh"
+	shellTcsh   shell = "tcsh"
+	shellCsh    shell = "csh"
+	shellElvish shell = "elvish"
+	shellCmd    shell = "cmd"
+)
+
+// shells lists the shells getgo writes for, as accepted by --shell
+var shells = []shell{shellSh, shellBash, shellZsh, shellFish, shellNu, shellPwsh, shellTcsh, shellCsh, shellElvish, shellCmd}
+
+// shellNames maps process and program names to shells
+var shellNames = map[string]shell{
+	"sh":         shellSh,
+	"dash":       shellSh,
+	"ash":        shellSh,
+	"ksh":        shellSh,
+	"mksh":       shellSh,
+	"yash":       shellSh,
+	"bash":       shellBash,
+	"zsh":        shellZsh,
+	"fish":       shellFish,
+	"nu":         shellNu,
+	"nushell":    shellNu,
+	"pwsh":       shellPwsh,
+	"powershell": shellPwsh,
+	"tcsh":       shellTcsh,
+	"csh":        shellCsh,
+	"elvish":     shellElvish,
+	"cmd":        shellCmd,
+}
+
+// parseShell returns the shell for a process or program name such as "-zsh", "/usr/bin/fish" or "pwsh.exe"
+func parseShell(name string) (shell, bool) {
+	name = strings.TrimPrefix(filepath.Base(strings.TrimSpace(name)), "-")
+	name = strings.TrimSuffix(strings.ToLower(name), ".exe")
+	sh, ok := shellNames[name]
+	return sh, ok
+}
+
+// detectShell returns the shell getgo was started from. The parent process is checked first,
+// as $SHELL names the login shell, which is not the one in use after running e.g. fish from bash.
+func detectShell() shell {
+	if sh, ok := parseShell(parentProcessName()); ok {
+		return sh
+	}
+	if sh, ok := parseShell(os.Getenv("SHELL")); ok {
+		return sh
+	}
+	if runtime.GOOS == "windows" {
+		return shellCmd
+	}
+	return shellSh
+}
+
+// shellForFile returns the shell a configuration file is written for, judged by its name
+func shellForFile(path string) (shell, bool) {
+	name := filepath.Base(path)
+	switch {
+	case strings.HasSuffix(name, ".fish"):
+		return shellFish, true
+	case strings.HasSuffix(name, ".nu"):
+		return shellNu, true
+	case strings.HasSuffix(name, ".ps1"):
+		return shellPwsh, true
+	case strings.HasSuffix(name, ".elv"):
+		return shellElvish, true
+	case name == ".tcshrc":
+		return shellTcsh, true
+	case name == ".cshrc" || name == ".login":
+		return shellCsh, true
+	case strings.HasPrefix(name, ".zsh"):
+		return shellZsh, true
+	case strings.HasPrefix(name, ".bash"):
+		return shellBash, true
+	case name == ".profile" || name == ".envrc":
+		return shellSh, true
+	}
+	return "", false
+}
+
+// configFile returns the configuration file of the shell in the home directory
+func (s shell) configFile(home string) string {
+	switch s {
+	case shellZsh:
+		return filepath.Join(home, ".zshrc")
+	case shellBash:
+		// Check for .bash_profile first on macOS
+		if runtime.GOOS == "darwin" {
+			bashProfile := filepath.Join(home, ".bash_profile")
+			if _, err := os.Stat(bashProfile); err == nil {
+				return bashProfile
+			}
+		}
+		return filepath.Join(home, ".bashrc")
+	case shellFish:
+		return filepath.Join(home, ".config", "fish", "config.fish")
+	case shellNu:
+		// nushell keeps its configuration in the platform's configuration directory
+		if dir, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(dir, "nushell", "env.nu")
+		}
+		return filepath.Join(home, ".config", "nushell", "env.nu")
+	case shellPwsh:
+		if runtime.GOOS == "windows" {
+			return filepath.Join(home, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1")
+		}
+		return filepath.Join(home, ".config", "powershell", "Microsoft.PowerShell_profile.ps1")
+	case shellTcsh:
+		// tcsh reads .cshrc when there is no .tcshrc
+		tcshrc := filepath.Join(home, ".tcshrc")
+		if cshrc := filepath.Join(home, ".cshrc"); !fileExists(tcshrc) && fileExists(cshrc) {
+			return cshrc
+		}
+		return tcshrc
+	case shellCsh:
+		return filepath.Join(home, ".cshrc")
+	case shellElvish:
+		// Older elvish versions read ~/.elvish/rc.elv
+		if legacy := filepath.Join(home, ".elvish", "rc.elv"); fileExists(legacy) {
+			return legacy
+		}
+		return filepath.Join(home, ".config", "elvish", "rc.elv")
+	case shellCmd:
+		// cmd has no configuration file; the variables live in the user environment
+		return ""
+	}
+
+	// Try to find a common shell configuration file
+	for _, file := range []string{".profile", ".bashrc", ".bash_profile", ".zshrc"} {
+		path := filepath.Join(home, file)
+		if fileExists(path) {
+			return path
+		}
+	}
+	// Default to .profile if no other file is found
+	return filepath.Join(home, ".profile")
+}
+
+// fileExists reports whether a file exists at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// shellConfigFiles returns the configuration files getgo may have written to for any shell
+func shellConfigFiles(home string) []string {
+	files := []string{
+		filepath.Join(home, ".bashrc"),
+		filepath.Join(home, ".bash_profile"),
+		filepath.Join(home, ".zshrc"),
+		filepath.Join(home, ".profile"),
+		filepath.Join(home, ".tcshrc"),
+		filepath.Join(home, ".cshrc"),
+		filepath.Join(home, ".elvish", "rc.elv"),
+	}
+	for _, sh := range []shell{shellFish, shellNu, shellPwsh} {
+		files = append(files, sh.configFile(home))
+	}
+	return append(files, filepath.Join(home, ".config", "elvish", "rc.elv"))
+}
+
+// setenv returns the line that sets and exports the variable name to value
+func (s shell) setenv(name, value string) string {
+	switch s {
+	case shellFish:
+		return fmt.Sprintf("set -gx %s %s", name, s.quote(value))
+	case shellNu:
+		return fmt.Sprintf("$env.%s = %s", name, s.quote(value))
+	case shellPwsh:
+		return fmt.Sprintf("$env:%s = %s", name, s.quote(value))
+	case shellTcsh, shellCsh:
+		return fmt.Sprintf("setenv %s %s", name, s.quote(value))
+	case shellElvish:
+		return fmt.Sprintf("set E:%s = %s", name, s.quote(value))
+	case shellCmd:
+		return fmt.Sprintf(`set "%s=%s"`, name, value)
+	}
+	return fmt.Sprintf("export %s=%s", name, s.quote(value))
+}
+
+// unsetenv returns the line that removes the variable name from the environment
+func (s shell) unsetenv(name string) string {
+	switch s {
+	case shellFish:
+		return "set -e " + name
+	case shellNu:
+		return "hide-env --ignore-errors " + name
+	case shellPwsh:
+		return fmt.Sprintf("Remove-Item Env:%s -ErrorAction SilentlyContinue", name)
+	case shellTcsh, shellCsh:
+		return "unsetenv " + name
+	case shellElvish:
+		return "unset-env " + name
+	case shellCmd:
+		return fmt.Sprintf("set %s=", name)
+	}
+	return "unset " + name
+}
+
+// setPath returns the line that sets PATH to the directories dirs
+func (s shell) setPath(dirs []string) string {
+	var quoted []string
+	for _, dir := range dirs {
+		quoted = append(quoted, s.quote(dir))
+	}
+
+	// fish, nushell and elvish keep PATH as a list
+	switch s {
+	case shellFish:
+		return "set -gx PATH " + strings.Join(quoted, " ")
+	case shellNu:
+		return "$env.PATH = [" + strings.Join(quoted, " ") + "]"
+	case shellElvish:
+		return "set paths = [" + strings.Join(quoted, " ") + "]"
+	}
+	return s.setenv("PATH", strings.Join(dirs, string(os.PathListSeparator)))
+}
+
+// appendBinDirs returns the line that appends the bin directory under each of the variables to PATH
+func (s shell) appendBinDirs(vars ...string) string {
+	var dirs []string
+	for _, v := range vars {
+		switch s {
+		case shellFish:
+			dirs = append(dirs, fmt.Sprintf("$%s/bin", v))
+		case shellNu:
+			dirs = append(dirs, fmt.Sprintf("append ($env.%s | path join bin)", v))
+		case shellPwsh:
+			dirs = append(dirs, fmt.Sprintf("(Join-Path $env:%s bin)", v))
+		case shellTcsh, shellCsh:
+			dirs = append(dirs, fmt.Sprintf("${%s}/bin", v))
+		case shellElvish:
+			dirs = append(dirs, fmt.Sprintf("$E:%s/bin", v))
+		case shellCmd:
+			dirs = append(dirs, fmt.Sprintf(`%%%s%%\bin`, v))
+		default:
+			dirs = append(dirs, fmt.Sprintf("$%s/bin", v))
+		}
+	}
+
+	switch s {
+	case shellFish:
+		// fish_add_path skips directories that are already in PATH, so sourcing the file twice is harmless
+		return "fish_add_path --append --path " + strings.Join(dirs, " ")
+	case shellNu:
+		return fmt.Sprintf("$env.PATH = ($env.PATH | split row (char esep) | %s)", strings.Join(dirs, " | "))
+	case shellPwsh:
+		// Joining a list avoids adding strings to the [char] separator, which PowerShell rejects
+		return fmt.Sprintf("$env:PATH = @($env:PATH, %s) -join [IO.Path]::PathSeparator", strings.Join(dirs, ", "))
+	case shellTcsh, shellCsh:
+		return fmt.Sprintf(`setenv PATH "${PATH}:%s"`, strings.Join(dirs, ":"))
+	case shellElvish:
+		return fmt.Sprintf("set paths = [$@paths %s]", strings.Join(dirs, " "))
+	case shellCmd:
+		return fmt.Sprintf(`set "PATH=%%PATH%%;%s"`, strings.Join(dirs, ";"))
+	}
+	return "export PATH=$PATH:" + strings.Join(dirs, ":")
+}
+
+// goEnv returns the lines that set up the environment for goroot and gopath
+func (s shell) goEnv(goroot, gopath string) []string {
+	return []string{
+		s.setenv("GOROOT", goroot),
+		s.setenv("GOPATH", gopath),
+		s.appendBinDirs("GOPATH", "GOROOT"),
+	}
+}
+
+// sourceCommand returns the command that applies a changed configuration file to the running
+// shell, or "" if the shell has to be restarted
+func (s shell) sourceCommand(file string) string {
+	switch s {
+	case shellPwsh:
+		return ". " + s.quote(file)
+	case shellNu, shellElvish, shellCmd:
+		return ""
+	}
+	return "source " + s.quote(file)
+}
+
+// isShellSafe reports whether value needs no quoting in any shell
+func isShellSafe(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("_-./:,+@", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// quote returns value as a single word in the syntax of the shell
+func (s shell) quote(value string) string {
+	switch s {
+	case shellNu:
+		// Raw strings take any content but the delimiter
+		if !strings.Contains(value, "'") {
+			return "'" + value + "'"
+		}
+		return "r#'" + value + "'#"
+	case shellPwsh:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case shellCmd:
+		return `"` + value + `"`
+	}
+
+	if isShellSafe(value) {
+		return value
+	}
+	switch s {
+	case shellFish:
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+	case shellElvish:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case shellTcsh, shellCsh:
+		// History substitution applies even inside single quotes
+		return "'" + strings.NewReplacer("'", `'\''`, "!", `\!`).Replace(value) + "'"
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// shimTools are the executables getgo installs shims for
+var shimTools = []string{"go", "gofmt"}
+
+// shimName returns the tool name if getgo was invoked through a shim, or ""
+func shimName(arg0 string) string {
+	name := strings.TrimSuffix(filepath.Base(arg0), ".exe")
+	for _, tool := range shimTools {
+		if name == tool {
+			return name
+		}
+	}
+	return ""
+}
+
+// shimRequirement determines the Go version a shim should run in dir
+func shimRequirement(cfg *Config, dir string) (*versionRequirement, error) {
+	req, err := findVersionRequirement(dir, cfg.ShimOrder)
+	if err != nil {
+		return nil, err
+	}
+	if req != nil {
+		return req, nil
+	}
+
+	// Fall back to the configured default, then to the current symlink
+	if cfg.DefaultVersion != "" {
+		return &versionRequirement{Spec: strings.TrimPrefix(cfg.DefaultVersion, "go"), Source: "default version"}, nil
+	}
+	if current := currentGoroot(cfg.Root); current != "" {
+		if version, err := readGoVersionFile(current); err == nil {
+			return &versionRequirement{Spec: version, Source: currentLinkPath(cfg.Root)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no Go version found: no %s above %s and no default version configured",
+		strings.Join(cfg.ShimOrder, ", "), dir)
+}
+
+// shimToolchain finds the installed toolchain for dir, installing it if configured to
+func shimToolchain(ctx context.Context, cfg *Config, dir string) (*installedGo, *versionRequirement, error) {
+	req, err := shimRequirement(cfg, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	goInst, err := matchInstalled(cfg.Root, req)
+	if err == nil {
+		return goInst, req, nil
+	}
+	if !cfg.AutoInstall {
+		return nil, req, fmt.Errorf("%s is not installed in %s; run 'getgo %s %s' or set GETGO_AUTO_INSTALL=1",
+			req, cfg.Root, req.installSpec(), cfg.Root)
+	}
+
+	// Keep the tool's stdout clean while installing, as callers may parse it
+	stdout, colorOutput := os.Stdout, color.Output
+	os.Stdout, color.Output = os.Stderr, color.Error
+	goroot, err := installGo(ctx, installOptions{versionSpec: req.installSpec(), installPath: cfg.Root})
+	os.Stdout, color.Output = stdout, colorOutput
+	if err != nil {
+		return nil, req, err
+	}
+
+	version, err := readGoVersionFile(goroot)
+	if err != nil {
+		return nil, req, err
+	}
+	return &installedGo{Version: version, Dir: goroot}, req, nil
+}
+
+// runShim runs the named tool from the toolchain selected for the working directory
+func runShim(ctx context.Context, name string, args []string) {
+	// Shim diagnostics go to stderr so they never mix with the tool's output
+	color.Output = color.Error
+
+	cfg, err := loadConfig()
+	if err != nil {
+		color.Red("getgo: %v", err)
+		os.Exit(1)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		color.Red("getgo: %v", err)
+		os.Exit(1)
+	}
+
+	goInst, _, err := shimToolchain(ctx, cfg, dir)
+	exitIfCanceled(ctx)
+	if err != nil {
+		color.Red("getgo: %v", err)
+		os.Exit(1)
+	}
+
+	tool := filepath.Join(goInst.Dir, "bin", name)
+	if runtime.GOOS == "windows" {
+		tool += ".exe"
+	}
+
+	// Make sure the tool does not pick up a GOROOT of another toolchain
+	env := toolchainEnv(os.Environ(), goInst.Dir)
+	if err := execTool(tool, args, env); err != nil {
+		color.Red("getgo: error running %s: %v", tool, err)
+		os.Exit(1)
+	}
+}
+
+// toolchainEnv returns environ set up to run the toolchain at goroot. Any GOROOT entry is replaced,
+// as the first of duplicate entries wins, and goroot/bin is put first in PATH so that tools the
+// toolchain starts, like go starting gofmt, come from the same version.
+func toolchainEnv(environ []string, goroot string) []string {
+	// Windows environment variable names are case-insensitive
+	isVar := func(entry, name string) bool {
+		key, _, _ := strings.Cut(entry, "=")
+		if runtime.GOOS == "windows" {
+			return strings.EqualFold(key, name)
+		}
+		return key == name
+	}
+
+	bin := filepath.Join(goroot, "bin")
+	env := []string{"GOROOT=" + goroot}
+	pathSet := false
+	for _, entry := range environ {
+		switch {
+		case isVar(entry, "GOROOT"):
+		case isVar(entry, "PATH") && !pathSet:
+			key, value, _ := strings.Cut(entry, "=")
+			env = append(env, key+"="+bin+string(os.PathListSeparator)+value)
+			pathSet = true
+		case isVar(entry, "PATH"):
+		default:
+			env = append(env, entry)
+		}
+	}
+	if !pathSet {
+		env = append(env, "PATH="+bin)
+	}
+	return env
+}
+
+// printShimUsage prints the usage information for the shim command
+func printShimUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Printf("%s: getgo shim <install|remove|which> [options] [install_path]\n", bold("Usage"))
+	fmt.Printf("%s:\n", bold("Examples"))
+	fmt.Printf("  %s        # Install go and gofmt shims into ~/.go/bin\n", cyan("getgo shim install ~/.go"))
+	fmt.Printf("  %s             # Show which toolchain the shims select here\n", cyan("getgo shim which"))
+	fmt.Printf("  %s  # Remove the shims from ~/bin\n", cyan("getgo shim remove --bin ~/bin"))
+
+	fmt.Printf("\n%s:\n", bold("Options"))
+	fmt.Printf("  --bin DIR          Directory for the shims (default: install_path/bin)\n")
+	fmt.Printf("  -f, --force        Overwrite existing files that are not getgo shims\n")
+
+	fmt.Printf("\n%s:\n", bold("Resolution"))
+	fmt.Printf("  Shims walk up from the working directory looking for .go-version, go.work and go.mod\n")
+	fmt.Printf("  (toolchain directive first, then go). Settings come from the configuration file\n")
+	fmt.Printf("  or the environment:\n")
+	fmt.Printf("  GETGO_ROOT             Install root holding go<version> trees (default: ~/.go)\n")
+	fmt.Printf("  GETGO_SHIM_ORDER       Comma-separated files to look for (default: .go-version,go.work,go.mod)\n")
+	fmt.Printf("  GETGO_DEFAULT_VERSION  Version used outside of projects (default: the install_path/go symlink)\n")
+	fmt.Printf("  GETGO_AUTO_INSTALL     Set to 1 to install missing versions instead of failing\n")
+}
+
+// runShimCommand manages the go and gofmt shims
+func runShimCommand(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("shim", flag.ExitOnError)
+	fs.Usage = printShimUsage
+	binFlag := fs.String("bin", "", "Directory for the shims")
+	forceFlag := fs.Bool("force", false, "Overwrite existing files that are not getgo shims")
+	fFlag := fs.Bool("f", false, "Overwrite existing files that are not getgo shims (shorthand)")
+	args = parseArgs(fs, args)
+
+	if len(args) < 1 || len(args) > 2 {
+		printShimUsage()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+
+	// An explicit install path becomes the root the shims use
+	if len(args) == 2 {
+		cfg.Root = expandPathOrExit(args[1])
+	}
+
+	binDir := filepath.Join(cfg.Root, "bin")
+	if *binFlag != "" {
+		binDir = expandPathOrExit(*binFlag)
+	}
+
+	switch args[0] {
+	case "install":
+		if err := installShims(binDir, *forceFlag || *fFlag); err != nil {
+			color.Red("Error installing shims: %v", err)
+			os.Exit(1)
+		}
+		if len(args) == 2 {
+			// Save only the root, not the GETGO_* overrides of this run
+			fileCfg, err := readConfigFile()
+			if err == nil {
+				fileCfg.Root = cfg.Root
+				err = saveConfig(fileCfg)
+			}
+			if err != nil {
+				color.Red("Error saving configuration: %v", err)
+				os.Exit(1)
+			}
+		}
+		color.Green("Installed %s shims into %s (install root: %s)", strings.Join(shimTools, " and "), binDir, cfg.Root)
+		color.Yellow("Put %s before any other Go installation in your PATH", binDir)
+
+	case "remove":
+		if err := removeShims(binDir); err != nil {
+			color.Red("Error removing shims: %v", err)
+			os.Exit(1)
+		}
+		color.Green("Removed shims from %s", binDir)
+
+	case "which":
+		dir, err := os.Getwd()
+		if err != nil {
+			color.Red("Error: %v", err)
+			os.Exit(1)
+		}
+		req, err := shimRequirement(cfg, dir)
+		if err != nil {
+			color.Red("Error: %v", err)
+			os.Exit(1)
+		}
+		goInst, err := matchInstalled(cfg.Root, req)
+		if err != nil {
+			color.Red("Error: %v", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Go %s (%s) selected by %s\n", goInst.Version, goInst.Dir, req)
+
+	default:
+		printShimUsage()
+		os.Exit(1)
+	}
+}
+
+// isShim reports whether path is a shim created by installShims
+func isShim(path, exe string) bool {
+	if target, err := os.Readlink(path); err == nil {
+		return samePath(target, exe) || strings.HasPrefix(filepath.Base(target), "getgo")
+	}
+	// Copied shims (Windows) are identical in size to the getgo executable
+	info, err := os.Stat(path)
+	exeInfo, exeErr := os.Stat(exe)
+	return err == nil && exeErr == nil && info.Size() == exeInfo.Size()
+}
+
+// installShims creates go and gofmt shims in binDir that point at the getgo executable
+func installShims(binDir string, force bool) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return err
+	}
+
+	for _, tool := range shimTools {
+		path := filepath.Join(binDir, tool)
+		if runtime.GOOS == "windows" {
+			path += ".exe"
+		}
+
+		if _, err := os.Lstat(path); err == nil {
+			if !force && !isShim(path, exe) {
+				return fmt.Errorf("%s already exists and is not a getgo shim (use --force to overwrite)", path)
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+
+		// Symlinks need extra privileges on Windows, so shims are copies there
+		if runtime.GOOS == "windows" {
+			err = copyFile(exe, path, 0755)
+		} else {
+			err = os.Symlink(exe, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeShims deletes the shims created by installShims from binDir
+func removeShims(binDir string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	for _, tool := range shimTools {
+		path := filepath.Join(binDir, tool)
+		if runtime.GOOS == "windows" {
+			path += ".exe"
+		}
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		if !isShim(path, exe) {
+			color.Yellow("Skipping %s: not a getgo shim", path)
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies src to dst with the given permissions
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
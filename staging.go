@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// stagePrefix names the directories toolchains are extracted into, inside the install root
+	stagePrefix = ".getgo-stage-"
+
+	// backupPrefix names an installation that is being replaced, until the new one is in place
+	backupPrefix = ".getgo-backup-"
+)
+
+// newStagingDir creates a staging directory inside installPath, after cleaning up what interrupted
+// installs left behind. Staging on the same file system as the final location means the finished
+// tree is moved into place with a single rename.
+func newStagingDir(installPath string) (string, error) {
+	if err := os.MkdirAll(installPath, 0755); err != nil {
+		return "", fmt.Errorf("error creating installation directory: %v", err)
+	}
+	recoverInterruptedInstalls(installPath)
+
+	// The process ID tells later runs whether the owner of the directory is still working on it
+	dir, err := os.MkdirTemp(installPath, fmt.Sprintf("%s%d-", stagePrefix, os.Getpid()))
+	if err != nil {
+		return "", fmt.Errorf("error creating staging directory: %v", err)
+	}
+	return dir, nil
+}
+
+// parseStagingName splits a staging or backup directory name after its prefix into the ID of
+// the process that created it and the rest of the name
+func parseStagingName(name, prefix string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return 0, "", false
+	}
+	pidStr, rest, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, "", false
+	}
+	pid, err := strconv.Atoi(pidStr)
+	if err != nil {
+		return 0, "", false
+	}
+	return pid, rest, true
+}
+
+// recoverInterruptedInstalls cleans up after installs into installPath that were killed midway.
+// Staging directories are removed. A backup is restored if the new tree never made it into
+// place, and removed otherwise. Directories of installs that are still running are left alone.
+func recoverInterruptedInstalls(installPath string) {
+	entries, err := os.ReadDir(installPath)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(installPath, entry.Name())
+
+		if pid, _, ok := parseStagingName(entry.Name(), stagePrefix); ok && !processAlive(pid) {
+			if err := os.RemoveAll(path); err == nil {
+				color.Yellow("Removed %s left by an interrupted install", path)
+			}
+			continue
+		}
+
+		pid, target, ok := parseStagingName(entry.Name(), backupPrefix)
+		if !ok || processAlive(pid) || !strings.HasPrefix(target, "go") {
+			continue
+		}
+		targetPath := filepath.Join(installPath, target)
+		if _, err := os.Lstat(targetPath); os.IsNotExist(err) {
+			if err := os.Rename(path, targetPath); err == nil {
+				color.Yellow("Restored %s, which an interrupted install was replacing", targetPath)
+			}
+		} else if err := os.RemoveAll(path); err == nil {
+			color.Yellow("Removed %s, the previous installation left by an interrupted reinstall", path)
+		}
+	}
+}
+
+// swapIntoPlace moves a staged Go tree to its versioned directory. An existing installation is
+// renamed to a backup first and only removed once the new tree is in place; if that fails, the
+// backup is put back. Should the process die in between, the next run restores the backup.
+// Cancellation is only honored before the swap starts; once started, it runs to completion.
+func swapIntoPlace(ctx context.Context, stagedGoDir, versionedGoDir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	backup := ""
+	if _, err := os.Lstat(versionedGoDir); err == nil {
+		backup = filepath.Join(filepath.Dir(versionedGoDir),
+			fmt.Sprintf("%s%d-%s", backupPrefix, os.Getpid(), filepath.Base(versionedGoDir)))
+		if err := os.Rename(versionedGoDir, backup); err != nil {
+			return fmt.Errorf("error moving the existing installation aside: %v", err)
+		}
+	}
+
+	if err := os.Rename(stagedGoDir, versionedGoDir); err != nil {
+		if backup != "" {
+			if restoreErr := os.Rename(backup, versionedGoDir); restoreErr != nil {
+				return fmt.Errorf("error moving extracted directory: %v (previous installation kept at %s: %v)", err, backup, restoreErr)
+			}
+		}
+		return fmt.Errorf("error moving extracted directory: %v", err)
+	}
+
+	if backup != "" {
+		if err := os.RemoveAll(backup); err != nil {
+			color.Yellow("Could not remove the previous installation at %s: %v", backup, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/dirhash"
+	"golang.org/x/mod/sumdb/note"
+)
+
+const testModVersion = "v0.0.1-go1.22.3.linux-amd64"
+
+// writeModuleZip writes a toolchain module zip holding a VERSION file with the given content
+func writeModuleZip(t *testing.T, version string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), testModVersion+".zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(toolchainModule + "@" + testModVersion + "/VERSION")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(version)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// startSumDB serves a checksum database signed with skey that records zipHash for any version
+// of the toolchain module, and returns its URL
+func startSumDB(t *testing.T, skey, zipHash string) string {
+	t.Helper()
+	gosum := func(path, vers string) ([]byte, error) {
+		if path != toolchainModule {
+			return nil, fmt.Errorf("no such module: %s@%s", path, vers)
+		}
+		return []byte(fmt.Sprintf("%[1]s %[2]s %[3]s\n%[1]s %[2]s/go.mod h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=\n",
+			path, vers, zipHash)), nil
+	}
+	srv := httptest.NewServer(sumdb.NewServer(sumdb.NewTestServer(skey, gosum)))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// generateSumDBKey returns a new signer and verifier key for a test database
+func generateSumDBKey(t *testing.T) (skey, vkey string) {
+	t.Helper()
+	skey, vkey, err := note.GenerateKey(rand.Reader, "sum.getgo.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return skey, vkey
+}
+
+// setupSumDBEnv points GOSUMDB at the database and gives each test its own cache
+func setupSumDBEnv(t *testing.T, vkey, url string) {
+	t.Setenv("GETGO_CACHE", t.TempDir())
+	t.Setenv("GOSUMDB", vkey+" "+url)
+	t.Setenv("GONOSUMDB", "")
+	t.Setenv("GOPRIVATE", "")
+}
+
+func TestLookupToolchainHash(t *testing.T) {
+	zipPath := writeModuleZip(t, "go1.22.3")
+	zipHash, err := dirhash.HashZip(zipPath, dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	skey, vkey := generateSumDBKey(t)
+	setupSumDBEnv(t, vkey, startSumDB(t, skey, zipHash))
+
+	hash, err := lookupToolchainHash(context.Background(), testModVersion)
+	if err != nil {
+		t.Fatalf("lookupToolchainHash: %v", err)
+	}
+	if hash != zipHash {
+		t.Fatalf("lookupToolchainHash = %s, want %s", hash, zipHash)
+	}
+	if err := verifyModuleZip(zipPath, hash); err != nil {
+		t.Fatalf("verifyModuleZip: %v", err)
+	}
+
+	// The verified tree head is kept for the consistency check of the next lookup
+	cache, _ := cacheDir()
+	if _, err := os.Stat(filepath.Join(cache, "sumdb", "sum.getgo.test", "latest")); err != nil {
+		t.Errorf("tree head not stored: %v", err)
+	}
+}
+
+func TestLookupToolchainHashBadSignature(t *testing.T) {
+	skey, _ := generateSumDBKey(t)
+	_, otherVkey := generateSumDBKey(t)
+	setupSumDBEnv(t, otherVkey, startSumDB(t, skey, "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="))
+
+	if hash, err := lookupToolchainHash(context.Background(), testModVersion); err == nil {
+		t.Fatalf("lookupToolchainHash = %s, want an error for a tree head signed by another key", hash)
+	}
+}
+
+func TestLookupToolchainHashForkedTree(t *testing.T) {
+	skey, vkey := generateSumDBKey(t)
+	setupSumDBEnv(t, vkey, startSumDB(t, skey, "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="))
+	if _, err := lookupToolchainHash(context.Background(), testModVersion); err != nil {
+		t.Fatalf("lookupToolchainHash: %v", err)
+	}
+
+	// A database signed with the same key but holding another record presents a tree head
+	// that conflicts with the one seen before
+	t.Setenv("GOSUMDB", vkey+" "+startSumDB(t, skey, "h1:BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB="))
+	hash, err := lookupToolchainHash(context.Background(), "v0.0.1-go1.22.4.linux-amd64")
+	// The sumdb client wraps ErrSecurity with %v, so only its message is left to check
+	if err == nil || !strings.Contains(err.Error(), sumdb.ErrSecurity.Error()) {
+		t.Fatalf("lookupToolchainHash = %q, %v, want %v", hash, err, sumdb.ErrSecurity)
+	}
+}
+
+func TestVerifyModuleZipMismatch(t *testing.T) {
+	want, err := dirhash.HashZip(writeModuleZip(t, "go1.22.3"), dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyModuleZip(writeModuleZip(t, "go1.22.3-tampered"), want)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("verifyModuleZip = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestLookupToolchainHashDisabled(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"GOSUMDB=off", map[string]string{"GOSUMDB": "off"}},
+		{"GONOSUMDB", map[string]string{"GONOSUMDB": "golang.org/toolchain"}},
+		{"GONOSUMDB prefix", map[string]string{"GONOSUMDB": "golang.org"}},
+		{"GOPRIVATE", map[string]string{"GOPRIVATE": "golang.org/*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No database is running, so any lookup attempt would fail differently
+			setupSumDBEnv(t, "sum.getgo.test+00000000+AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", "http://127.0.0.1:0")
+			os.Unsetenv("GONOSUMDB")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := lookupToolchainHash(context.Background(), testModVersion)
+			if !errors.Is(err, errNoSumDB) {
+				t.Fatalf("lookupToolchainHash error = %v, want %v", err, errNoSumDB)
+			}
+		})
+	}
+}
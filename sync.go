@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/fatih/color"
+)
+
+// syncFiles are the project files read by sync, in order of precedence
+var syncFiles = []string{".go-version", ".tool-versions", "go.work", "go.mod"}
+
+// printSyncUsage prints the usage information for the sync command
+func printSyncUsage() {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Printf("%s: getgo sync [options] [dir]\n", bold("Usage"))
+	fmt.Printf("%s:\n", bold("Examples"))
+	fmt.Printf("  %s                # Install the Go version the current project requires\n", cyan("getgo sync"))
+	fmt.Printf("  %s     # Same for ~/src/service, into /opt/go\n", cyan("getgo sync --root /opt/go ~/src/service"))
+
+	fmt.Printf("\n%s:\n", bold("Options"))
+	fmt.Printf("  --root PATH        Install root (default: configured root, %s)\n", defaultConfig().Root)
+	fmt.Printf("  -p, --path PATH    Set custom GOPATH for the .envrc file (default is $HOME/go)\n")
+	fmt.Printf("  --no-envrc         Do not create or update the project's .envrc file\n")
+	fmt.Printf("  --no-verify        Skip checksum verification (release manifest or checksum database)\n")
+	fmt.Printf("  --stream           Extract .tar.gz archives while downloading, without saving them first\n")
+	fmt.Printf("  --offline          Install from the cached manifest and archives only\n")
+	fmt.Printf("  --mirror URL       Download mirror to try before falling back to the next (or GETGO_MIRRORS)\n")
+	fmt.Printf("  --source SOURCE    Download from dl (go.dev or mirrors) or proxy (GOPROXY)\n")
+
+	fmt.Printf("\nThe version is read from .go-version, .tool-versions (asdf), go.work or go.mod\n")
+	fmt.Printf("(toolchain directive first, then go), searching upwards from dir.\n")
+}
+
+// runSync installs the Go version a project requires and refreshes its .envrc file
+func runSync(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	fs.Usage = printSyncUsage
+	rootFlag := fs.String("root", "", "Install root")
+	gopathFlag := fs.String("path", "", "Custom GOPATH (default is $HOME/go)")
+	gopathShortFlag := fs.String("p", "", "Custom GOPATH (shorthand)")
+	noEnvrcFlag := fs.Bool("no-envrc", false, "Do not create or update the .envrc file")
+	noVerifyFlag := fs.Bool("no-verify", false, "Skip checksum verification of the downloaded archive")
+	streamFlag := fs.Bool("stream", false, "Extract .tar.gz archives while downloading")
+	fs.BoolVar(&offline, "offline", offline, "Install from the download cache only")
+	fs.Var(&mirrorFlag, "mirror", "Download mirror base URL (repeatable, comma-separated)")
+	fs.StringVar(&sourceFlag, "source", "", "Download from dl (go.dev or mirrors) or proxy (GOPROXY)")
+	args = parseArgs(fs, args)
+
+	dir := "."
+	switch len(args) {
+	case 0:
+	case 1:
+		dir = args[0]
+	default:
+		printSyncUsage()
+		os.Exit(1)
+	}
+	dir = expandPathOrExit(dir)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+	if *rootFlag != "" {
+		cfg.Root = expandPathOrExit(*rootFlag)
+	}
+
+	req, err := findVersionRequirement(dir, syncFiles)
+	if err != nil {
+		color.Red("Error reading project files: %v", err)
+		os.Exit(1)
+	}
+	if req == nil {
+		color.Red("No Go version found in %v above %s", syncFiles, dir)
+		os.Exit(1)
+	}
+	color.Cyan("Project requires %s", req)
+
+	// Reuse an installed toolchain that already satisfies the requirement
+	goroot := ""
+	if goInst, err := matchInstalled(cfg.Root, req); err == nil {
+		color.Green("Go %s is already installed at %s", goInst.Version, goInst.Dir)
+		goroot = goInst.Dir
+	} else {
+		goroot, err = installGo(ctx, installOptions{
+			versionSpec: req.installSpec(),
+			installPath: cfg.Root,
+			noVerify:    *noVerifyFlag,
+			stream:      *streamFlag,
+		})
+		exitIfCanceled(ctx)
+		if err != nil {
+			color.Red("Error: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	warnConflictingRequirements(req, goroot)
+
+	if *noEnvrcFlag {
+		return
+	}
+
+	// Set GOPATH - use custom path if provided, otherwise default to $HOME/go
+	gopath := getCustomGOPATH(gopathFlag, gopathShortFlag)
+	if gopath != "" {
+		gopath = expandPathOrExit(gopath)
+	} else {
+		usr, err := user.Current()
+		if err != nil {
+			color.Red("Error getting current user: %v", err)
+			os.Exit(1)
+		}
+		gopath = filepath.Join(usr.HomeDir, "go")
+	}
+
+	// The .envrc belongs next to the file that declares the version
+	projectDir := filepath.Dir(req.Source)
+	if err := setupEnvrcFile(projectDir, goroot, gopath); err != nil {
+		color.Red("Error setting up .envrc file: %v", err)
+		os.Exit(1)
+	}
+	color.Yellow("Run 'direnv allow %s' to enable the environment variables", projectDir)
+}
+
+// warnConflictingRequirements warns about other project files next to req that goroot does not satisfy
+func warnConflictingRequirements(req *versionRequirement, goroot string) {
+	version, err := readGoVersionFile(goroot)
+	if err != nil {
+		return
+	}
+
+	for _, name := range syncFiles {
+		path := filepath.Join(filepath.Dir(req.Source), name)
+		if path == req.Source {
+			continue
+		}
+		other, err := readVersionFile(path)
+		if err != nil || other == nil {
+			continue
+		}
+		if !satisfies(version, other) {
+			color.Yellow("Warning: Go %s does not satisfy %s", version, other)
+		}
+	}
+}
+
+// satisfies reports whether the Go version satisfies req
+func satisfies(version string, req *versionRequirement) bool {
+	if req.Minimum {
+		return goSeries(version) == goSeries(req.Spec) && compareGoVersions(version, req.Spec) >= 0
+	}
+	if isConcreteVersion(req.Spec) {
+		return compareGoVersions(version, req.Spec) == 0
+	}
+	// A series such as "1.22" is satisfied by any release of that series
+	return goSeries(version) == goSeries(req.Spec)
+}
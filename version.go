@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	goversion "go/version"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// seriesSpec matches a minor series such as "1.22" or "1.22.x"
+	seriesSpec = regexp.MustCompile(`^(\d+\.\d+)(\.x)?$`)
+
+	// prereleaseSpec matches a prerelease kind, optionally limited to a series, such as "1.23rc" or "beta"
+	prereleaseSpec = regexp.MustCompile(`^(\d+\.\d+)?(rc|beta)$`)
+)
+
+// normalizeGoVersion returns v as "go1.x.y" so that go/version orders releases correctly.
+// Releases before Go 1.21 were published without a ".0" suffix (go1.20), which go/version
+// would otherwise read as a language version that sorts before go1.20rc1.
+func normalizeGoVersion(v string) string {
+	v = "go" + strings.TrimPrefix(v, "go")
+	if strings.Count(v, ".") == 1 && !isPrerelease(v) {
+		v += ".0"
+	}
+	return v
+}
+
+// isPrerelease reports whether v is a beta or release candidate
+func isPrerelease(v string) bool {
+	return strings.Contains(v, "rc") || strings.Contains(v, "beta")
+}
+
+// compareGoVersions compares two Go versions with or without the "go" prefix
+func compareGoVersions(a, b string) int {
+	return goversion.Compare(normalizeGoVersion(a), normalizeGoVersion(b))
+}
+
+// goSeries returns the minor series of v, e.g. "go1.22" for "1.22.3"
+func goSeries(v string) string {
+	return goversion.Lang(normalizeGoVersion(v))
+}
+
+// sortedGoVersions returns the valid versions from the manifest, newest first
+func sortedGoVersions(versions []GoVersion) []GoVersion {
+	sorted := make([]GoVersion, 0, len(versions))
+	for _, v := range versions {
+		if goversion.IsValid(normalizeGoVersion(v.Version)) {
+			sorted = append(sorted, v)
+		}
+	}
+	slices.SortStableFunc(sorted, func(a, b GoVersion) int {
+		return compareGoVersions(b.Version, a.Version)
+	})
+	return sorted
+}
+
+// isConcreteVersion reports whether spec names a single release rather than a keyword or series
+func isConcreteVersion(spec string) bool {
+	spec = strings.TrimPrefix(strings.ToLower(spec), "go")
+	switch spec {
+	case "", "-", "latest", "stable", "oldstable":
+		return false
+	}
+	if seriesSpec.MatchString(spec) || prereleaseSpec.MatchString(spec) {
+		return false
+	}
+	return goversion.IsValid(normalizeGoVersion(spec))
+}
+
+// resolveVersion resolves a version spec to a concrete release listed in the manifest.
+// Supported specs are "latest" (or "-" and "stable"), "oldstable", a minor series
+// such as "1.22" or "1.22.x", a prerelease kind such as "rc", "beta" or "1.23rc",
+// and exact versions such as "1.22.3" or "1.23rc1". The result has no "go" prefix.
+func resolveVersion(spec string, versions []GoVersion) (string, error) {
+	sorted := sortedGoVersions(versions)
+	if len(sorted) == 0 {
+		return "", fmt.Errorf("no Go versions found")
+	}
+
+	// newest returns the first (newest) version matching the predicate
+	newest := func(match func(v GoVersion) bool) (string, bool) {
+		for _, v := range sorted {
+			if match(v) {
+				return strings.TrimPrefix(v.Version, "go"), true
+			}
+		}
+		return "", false
+	}
+
+	spec = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(spec)), "go")
+
+	switch spec {
+	case "", "-", "latest", "stable":
+		if v, ok := newest(func(v GoVersion) bool { return v.Stable }); ok {
+			return v, nil
+		}
+		return "", fmt.Errorf("no stable Go version found")
+
+	case "oldstable":
+		latest, ok := newest(func(v GoVersion) bool { return v.Stable })
+		if !ok {
+			return "", fmt.Errorf("no stable Go version found")
+		}
+		if v, ok := newest(func(v GoVersion) bool {
+			return v.Stable && goSeries(v.Version) != goSeries(latest)
+		}); ok {
+			return v, nil
+		}
+		return "", fmt.Errorf("no oldstable Go version found")
+	}
+
+	if m := seriesSpec.FindStringSubmatch(spec); m != nil {
+		series := "go" + m[1]
+		if v, ok := newest(func(v GoVersion) bool {
+			return goSeries(v.Version) == series && !isPrerelease(v.Version)
+		}); ok {
+			return v, nil
+		}
+		return "", fmt.Errorf("no stable release found in the %s series (try %src)", series, m[1])
+	}
+
+	if m := prereleaseSpec.FindStringSubmatch(spec); m != nil {
+		if v, ok := newest(func(v GoVersion) bool {
+			if m[1] != "" && goSeries(v.Version) != "go"+m[1] {
+				return false
+			}
+			return strings.Contains(v.Version, m[2])
+		}); ok {
+			return v, nil
+		}
+		return "", fmt.Errorf("no %s prerelease found matching %q", m[2], spec)
+	}
+
+	if !goversion.IsValid(normalizeGoVersion(spec)) {
+		return "", fmt.Errorf("invalid Go version %q", spec)
+	}
+
+	if v, ok := newest(func(v GoVersion) bool { return compareGoVersions(v.Version, spec) == 0 }); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("go%s not found in the release manifest", spec)
+}